@@ -0,0 +1,103 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Analyzer suggests interface types for function parameters. It can be
+// used with any of the go/analysis drivers, such as go vet -vettool,
+// multichecker or gopls.
+var Analyzer = &analysis.Analyzer{
+	Name:      "interfacer",
+	Doc:       "suggest narrower interface types for function parameters",
+	Requires:  []*analysis.Analyzer{buildssa.Analyzer},
+	Run:       runAnalyzer,
+	FactTypes: []analysis.Fact{new(pkgIndex)},
+}
+
+// analyzerConfig holds the options set via the Analyzer's flags.
+var analyzerConfig Config
+
+func init() {
+	analyzerConfig.RegisterFlags(&Analyzer.Flags)
+}
+
+// pkgIndex is the fact exported for each analyzed package. It holds
+// the interfaces and func signatures declared in the package, so that
+// importers don't need to build them again from its scope.
+type pkgIndex struct {
+	// Ifaces holds the names of the interfaces for each method set.
+	Ifaces map[string][]string
+	Funcs  map[string]bool
+
+	// Generic holds the names of the generic interfaces, whose
+	// method sets depend on how they are instantiated.
+	Generic []string
+
+	// Deprecated holds the names of the types documented as
+	// deprecated, which are suggested last.
+	Deprecated []string
+}
+
+func (*pkgIndex) AFact() {}
+
+func (p *pkgIndex) String() string {
+	return fmt.Sprintf("%d interfaces, %d func signatures",
+		len(p.Ifaces)+len(p.Generic), len(p.Funcs))
+}
+
+func runAnalyzer(pass *analysis.Pass) (interface{}, error) {
+	if len(analyzerConfig.Dependents) > 0 {
+		// the driver only loads the packages being analyzed and
+		// their dependencies
+		return nil, fmt.Errorf("-rdeps is not supported by the analyzer")
+	}
+	own := fromScope(pass.Pkg.Scope())
+	own.Deprecated = deprecatedTypes(pass.Files)
+	pass.ExportPackageFact(own)
+
+	c := &Checker{
+		Config:   analyzerConfig,
+		pkg:      pass.Pkg,
+		Info:     pass.TypesInfo,
+		files:    pass.Files,
+		ssaByPos: make(map[token.Pos]*ssa.Function),
+		narrowed: make(map[*types.Var]types.Type),
+		rejected: make(map[*types.Var]bool),
+	}
+	c.index = func(pkg *types.Package) *pkgIndex {
+		idx := new(pkgIndex)
+		if pass.ImportPackageFact(pkg, idx) {
+			return idx
+		}
+		// no fact, e.g. if the driver didn't analyze this dependency
+		return fromScope(pkg.Scope())
+	}
+	ssaInfo := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	c.escaped = escapedFuncs(ssaInfo.SrcFuncs)
+	addInvokedMethods(c.escaped, ssaInfo.Pkg.Prog)
+	addBlankVarFuncs(c.escaped, pass.Files, pass.TypesInfo)
+	for _, fn := range ssaInfo.SrcFuncs {
+		if len(fn.Blocks) == 0 { // stub
+			continue
+		}
+		c.ssaByPos[fn.Pos()] = fn
+	}
+	c.getTypes(pass.Pkg, c.depth(), c.Pkgs, c.NoCatalog)
+	for _, issue := range c.checkPkg() {
+		pass.Report(analysis.Diagnostic{
+			Pos:     issue.Pos(),
+			Message: issue.Message(),
+		})
+	}
+	return nil, nil
+}
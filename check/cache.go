@@ -5,46 +5,305 @@ package check
 
 import (
 	"go/ast"
+	"go/build"
 	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 )
 
 type pkgTypes struct {
-	ifaces    map[string]string
+	// ifaces holds the interfaces in scope for each method set, best
+	// suggestion first.
+	ifaces    map[string][]*types.TypeName
 	funcSigns map[string]bool
+	ranker    *ifaceRanker
+
+	// implPkgs holds the packages depending on the checked one,
+	// whose interfaces may be implemented by its types too.
+	implPkgs []*types.Package
+
+	// byMethod holds all the interfaces in scope, exported or not, by
+	// the names of their methods.
+	byMethod map[string][]*types.Interface
+
+	// generic holds the generic interfaces in scope, which must be
+	// instantiated to be matched.
+	generic []*types.TypeName
+
+	// index returns the interfaces and func signatures declared in
+	// a package. If nil, they are read from the package's scope.
+	index func(*types.Package) *pkgIndex
+}
+
+func (p *pkgTypes) pkgIndex(pkg *types.Package) *pkgIndex {
+	if p.index != nil {
+		return p.index(pkg)
+	}
+	return fromScope(pkg.Scope())
 }
 
-func (p *pkgTypes) getTypes(pkg *types.Package) {
-	p.ifaces = make(map[string]string)
+// defaultDepth is the number of levels of imports searched for
+// interfaces by default; direct imports and their direct imports.
+const defaultDepth = 2
+
+// getTypes gathers the interfaces and func signatures in scope for pkg.
+// Its imports are searched depth levels deep, or through the whole
+// import graph if depth is negative. If only is non-empty, interfaces
+// are only taken from the packages matching its import paths, anywhere
+// in the import graph. Unless noCatalog is set, the interfaces in
+// stdCatalog are in scope too. The interfaces with the same methods are
+// ordered as per ifaceRanker.
+func (p *pkgTypes) getTypes(pkg *types.Package, depth int, only []string, noCatalog bool) {
+	p.ifaces = make(map[string][]*types.TypeName)
 	p.funcSigns = make(map[string]bool)
+	p.byMethod = make(map[string][]*types.Interface)
+	p.generic = nil
+	r := &ifaceRanker{
+		pkg:        pkg,
+		imported:   make(map[*types.Package]bool),
+		deprecated: make(map[*types.TypeName]bool),
+	}
+	for _, imp := range pkg.Imports() {
+		r.imported[imp] = true
+	}
+	p.ranker = r
+	defer r.sort(p.ifaces)
+	if !noCatalog {
+		p.addCatalog(pkg, only)
+	}
+	addIfaces := func(pkg *types.Package, idx *pkgIndex) {
+		if len(only) > 0 && !matchPkg(only, pkg.Path()) {
+			return
+		}
+		for _, name := range idx.Deprecated {
+			if tn, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok {
+				r.deprecated[tn] = true
+			}
+		}
+		for iftype, names := range idx.Ifaces {
+			for _, name := range names {
+				// only suggest exported interfaces
+				if !ast.IsExported(name) {
+					continue
+				}
+				if tn, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok {
+					p.addIface(iftype, tn)
+				}
+			}
+		}
+		for _, name := range idx.Generic {
+			if !ast.IsExported(name) {
+				continue
+			}
+			if tn, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok {
+				p.generic = append(p.generic, tn)
+			}
+		}
+	}
 	done := make(map[*types.Package]bool)
-	addTypes := func(pkg *types.Package, top bool) {
+	addTypes := func(pkg *types.Package) {
 		if done[pkg] {
 			return
 		}
 		done[pkg] = true
-		ifs, funs := fromScope(pkg.Scope())
-		fullName := func(name string) string {
-			if !top {
-				return pkg.Path() + "." + name
+		idx := p.pkgIndex(pkg)
+		addIfaces(pkg, idx)
+		p.addImpls(pkg, idx)
+	}
+	for _, dep := range p.implPkgs {
+		p.addImpls(dep, p.pkgIndex(dep))
+	}
+	// the depth left when each package was last walked
+	walked := make(map[*types.Package]int)
+	var walk func(pkg *types.Package, left int)
+	walk = func(pkg *types.Package, left int) {
+		if left == 0 {
+			return
+		}
+		if prev, e := walked[pkg]; e && (prev < 0 || (left > 0 && prev >= left)) {
+			return
+		}
+		walked[pkg] = left
+		addTypes(pkg)
+		for _, imp := range pkg.Imports() {
+			walk(imp, left-1)
+		}
+	}
+	for _, imp := range pkg.Imports() {
+		walk(imp, depth)
+	}
+	if len(only) > 0 {
+		// the listed packages may be beyond depth
+		seen := make(map[*types.Package]bool)
+		var find func(pkg *types.Package)
+		find = func(pkg *types.Package) {
+			if seen[pkg] {
+				return
+			}
+			seen[pkg] = true
+			if !done[pkg] {
+				addIfaces(pkg, p.pkgIndex(pkg))
 			}
-			return name
+			for _, imp := range pkg.Imports() {
+				find(imp)
+			}
+		}
+		for _, imp := range pkg.Imports() {
+			find(imp)
+		}
+	}
+	addTypes(pkg)
+}
+
+// addIface adds tn as an interface with the method set iftype, unless
+// it's already there.
+func (p *pkgTypes) addIface(iftype string, tn *types.TypeName) {
+	for _, other := range p.ifaces[iftype] {
+		if other == tn {
+			return
 		}
-		for iftype, name := range ifs {
-			// only suggest exported interfaces
-			if ast.IsExported(name) {
-				p.ifaces[iftype] = fullName(name)
+	}
+	p.ifaces[iftype] = append(p.ifaces[iftype], tn)
+}
+
+// addImpls records the interfaces and func signatures in a package's
+// index, exported or not, as ones that the checked package's funcs and
+// methods may be implementing.
+func (p *pkgTypes) addImpls(pkg *types.Package, idx *pkgIndex) {
+	for _, names := range idx.Ifaces {
+		for _, name := range names {
+			if tn, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok {
+				p.addImpl(tn.Type().Underlying().(*types.Interface))
 			}
 		}
-		for ftype := range funs {
-			// ignore non-exported func signatures too
-			p.funcSigns[ftype] = true
+	}
+	for ftype := range idx.Funcs {
+		p.funcSigns[ftype] = true
+	}
+}
+
+// addImpl records iface as an interface that the methods of the checked
+// package's types may be implementing.
+func (p *pkgTypes) addImpl(iface *types.Interface) {
+	for i := 0; i < iface.NumMethods(); i++ {
+		name := iface.Method(i).Name()
+		p.byMethod[name] = append(p.byMethod[name], iface)
+	}
+}
+
+// implements reports whether the receiver type of method implements an
+// interface in scope which has the method. Both the receiver type and a
+// pointer to it are considered, as either may be used as the
+// interface.
+func (p *pkgTypes) implements(method *types.Func) bool {
+	recv := method.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return false
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if types.IsInterface(t) {
+		return false
+	}
+	ptr := types.NewPointer(t)
+	for _, iface := range p.byMethod[method.Name()] {
+		if types.Implements(ptr, iface) {
+			return true
 		}
 	}
-	for _, imp := range pkg.Imports() {
-		addTypes(imp, false)
-		for _, imp2 := range imp.Imports() {
-			addTypes(imp2, false)
+	return false
+}
+
+// ifaceRanker orders the interfaces with the same method set by how
+// good a suggestion each is for a package: those in the standard
+// library come first, including the ones in scope only via stdCatalog,
+// then those in the packages it imports, then its own, and last those
+// further away in its import graph. Deprecated interfaces go after all
+// of those.
+type ifaceRanker struct {
+	pkg      *types.Package
+	imported map[*types.Package]bool
+
+	deprecated map[*types.TypeName]bool
+}
+
+func (r *ifaceRanker) rank(tn *types.TypeName) int {
+	var n int
+	switch {
+	case tn.Pkg() == nil:
+		// the universe error type
+	case inGoroot(tn.Pkg().Path()):
+	case r.imported[tn.Pkg()]:
+		n = 1
+	case tn.Pkg() == r.pkg:
+		n = 2
+	default:
+		n = 3
+	}
+	if r.deprecated[tn] {
+		n += 5
+	}
+	return n
+}
+
+func (r *ifaceRanker) sort(ifaces map[string][]*types.TypeName) {
+	for _, tns := range ifaces {
+		sort.SliceStable(tns, func(i, j int) bool {
+			ri, rj := r.rank(tns[i]), r.rank(tns[j])
+			if ri != rj {
+				return ri < rj
+			}
+			pi, pj := pkgPath(tns[i].Pkg()), pkgPath(tns[j].Pkg())
+			if pi != pj {
+				return pi < pj
+			}
+			return tns[i].Name() < tns[j].Name()
+		})
+	}
+}
+
+func pkgPath(pkg *types.Package) string {
+	if pkg == nil {
+		return ""
+	}
+	return pkg.Path()
+}
+
+var gorootPkgs sync.Map // import path to bool
+
+// inGoroot reports whether an import path belongs to the standard
+// library, by looking for its directory in GOROOT.
+func inGoroot(path string) bool {
+	if v, ok := gorootPkgs.Load(path); ok {
+		return v.(bool)
+	}
+	std := false
+	if isStd(path) && build.Default.GOROOT != "" {
+		dir := filepath.Join(build.Default.GOROOT, "src", filepath.FromSlash(path))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			std = true
+		}
+	}
+	gorootPkgs.Store(path, std)
+	return std
+}
+
+// matchPkg reports whether an import path matches any of the patterns,
+// which are either import paths or prefixes ending in "/...".
+func matchPkg(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if prefix := strings.TrimSuffix(pattern, "/..."); prefix != pattern {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return true
+			}
+		} else if path == pattern {
+			return true
 		}
 	}
-	addTypes(pkg, true)
+	return false
 }
@@ -0,0 +1,194 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"bufio"
+	"go/token"
+	"go/types"
+	"go/version"
+	"os"
+	"sync"
+
+	"golang.org/x/tools/go/gcexportdata"
+	"golang.org/x/tools/go/packages"
+)
+
+// stdIface is a well-known interface in the standard library.
+type stdIface struct {
+	path, name string
+
+	// since is the first Go version that has it, if after go1.
+	since string
+}
+
+// stdCatalog holds the standard library interfaces that are always in
+// scope, even if the package being checked doesn't import them. The
+// universe error type has an empty path.
+var stdCatalog = []stdIface{
+	{"", "error", ""},
+
+	{"io", "Reader", ""},
+	{"io", "Writer", ""},
+	{"io", "Closer", ""},
+	{"io", "Seeker", ""},
+	{"io", "ReadWriter", ""},
+	{"io", "ReadCloser", ""},
+	{"io", "WriteCloser", ""},
+	{"io", "ReadWriteCloser", ""},
+	{"io", "ReadSeeker", ""},
+	{"io", "WriteSeeker", ""},
+	{"io", "ReadWriteSeeker", ""},
+	{"io", "ReadSeekCloser", "go1.16"},
+	{"io", "ReaderAt", ""},
+	{"io", "WriterAt", ""},
+	{"io", "ReaderFrom", ""},
+	{"io", "WriterTo", ""},
+	{"io", "ByteReader", ""},
+	{"io", "ByteScanner", ""},
+	{"io", "ByteWriter", "go1.1"},
+	{"io", "RuneReader", ""},
+	{"io", "RuneScanner", ""},
+	{"io", "StringWriter", "go1.12"},
+
+	{"io/fs", "FS", "go1.16"},
+	{"io/fs", "File", "go1.16"},
+	{"io/fs", "ReadDirFile", "go1.16"},
+	{"io/fs", "FileInfo", "go1.16"},
+	{"io/fs", "DirEntry", "go1.16"},
+	{"io/fs", "GlobFS", "go1.16"},
+	{"io/fs", "ReadDirFS", "go1.16"},
+	{"io/fs", "ReadFileFS", "go1.16"},
+	{"io/fs", "StatFS", "go1.16"},
+	{"io/fs", "SubFS", "go1.16"},
+
+	{"fmt", "Stringer", ""},
+	{"fmt", "GoStringer", ""},
+	{"fmt", "Formatter", ""},
+
+	{"sort", "Interface", ""},
+
+	{"encoding", "TextMarshaler", "go1.2"},
+	{"encoding", "TextUnmarshaler", "go1.2"},
+	{"encoding", "BinaryMarshaler", "go1.2"},
+	{"encoding", "BinaryUnmarshaler", "go1.2"},
+
+	{"sync", "Locker", ""},
+
+	{"context", "Context", "go1.7"},
+}
+
+var (
+	stdExportsMu sync.Mutex
+	stdExports   map[string]string
+)
+
+// stdExportFile returns the file holding the export data of a package
+// in stdCatalog, as found by go list. It's empty if there is none.
+func stdExportFile(path string) string {
+	stdExportsMu.Lock()
+	defer stdExportsMu.Unlock()
+	if stdExports == nil {
+		stdExports = make(map[string]string)
+		var paths []string
+		for _, entry := range stdCatalog {
+			if entry.path != "" {
+				paths = append(paths, entry.path)
+			}
+		}
+		cfg := &packages.Config{Mode: packages.NeedName | packages.NeedExportFile}
+		if pkgs, err := packages.Load(cfg, paths...); err == nil {
+			for _, pkg := range pkgs {
+				stdExports[pkg.PkgPath] = pkg.ExportFile
+			}
+		}
+	}
+	return stdExports[path]
+}
+
+// importStd imports a standard library package from export data, for
+// when the package being checked doesn't depend on it. The packages it
+// depends on are taken from imports, keyed by path, so that the types
+// they declare are the same as in the package being checked; those
+// missing are added to it. It returns nil if the import fails.
+func importStd(path string, imports map[string]*types.Package) *types.Package {
+	name := stdExportFile(path)
+	if name == "" {
+		return nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	r, err := gcexportdata.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil
+	}
+	pkg, err := gcexportdata.Read(r, token.NewFileSet(), imports, path)
+	if err != nil {
+		return nil
+	}
+	return pkg
+}
+
+// addCatalog adds the interfaces in stdCatalog that are available to
+// pkg, as per its Go version. Those in its import graph are taken from
+// there, and the rest are imported separately, sharing the packages in
+// it.
+func (p *pkgTypes) addCatalog(pkg *types.Package, only []string) {
+	// the complete packages in the import graph, shared with those
+	// imported separately; incomplete ones, as loaded from export data
+	// by some drivers, may be shared with other goroutines too, so
+	// they aren't filled in
+	deps := make(map[string]*types.Package)
+	seen := make(map[*types.Package]bool)
+	var walk func(pkg *types.Package)
+	walk = func(pkg *types.Package) {
+		if seen[pkg] {
+			return
+		}
+		seen[pkg] = true
+		if pkg.Complete() {
+			deps[pkg.Path()] = pkg
+		}
+		for _, imp := range pkg.Imports() {
+			walk(imp)
+		}
+	}
+	walk(pkg)
+	goVersion := pkg.GoVersion()
+	for _, entry := range stdCatalog {
+		if len(only) > 0 && !matchPkg(only, entry.path) {
+			continue
+		}
+		if entry.since != "" && goVersion != "" && version.Compare(goVersion, entry.since) < 0 {
+			continue
+		}
+		scope := types.Universe
+		if entry.path != "" {
+			dep := deps[entry.path]
+			if dep == nil || !dep.Complete() {
+				// those imported separately may have added it
+				// with only the parts they use
+				if dep = importStd(entry.path, deps); dep == nil {
+					continue
+				}
+			}
+			scope = dep.Scope()
+		}
+		tn, ok := scope.Lookup(entry.name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		iface, ok := tn.Type().Underlying().(*types.Interface)
+		if !ok {
+			continue
+		}
+		p.addImpl(iface)
+		if s := ifaceSigns(iface, p.funcSigns); s != "" {
+			p.addIface(s, tn)
+		}
+	}
+}
@@ -4,19 +4,19 @@
 package check // import "mvdan.cc/interfacer/check"
 
 import (
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
-	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/ssa"
 	"golang.org/x/tools/go/ssa/ssautil"
-
-	"github.com/kisielk/gotool"
-	"mvdan.cc/lint"
 )
 
 func toDiscard(usage *varUsage) bool {
@@ -40,15 +40,82 @@ func allCalls(usage *varUsage, all, ftypes map[string]string) {
 	}
 }
 
-func (c *Checker) interfaceMatching(param *types.Var, usage *varUsage) (string, string) {
+// interfaceMatching returns the interface in scope with exactly the
+// methods called on param, along with its type arguments if generic.
+func (c *Checker) interfaceMatching(param *types.Var, usage *varUsage) (*types.TypeName, []types.Type, map[string]string) {
 	if toDiscard(usage) {
-		return "", ""
+		return nil, nil, nil
 	}
 	ftypes := typeFuncMap(param.Type())
 	called := make(map[string]string, len(usage.calls))
 	allCalls(usage, called, ftypes)
-	s := funcMapString(called)
-	return c.ifaces[s], s
+	tn, targs := c.matchCalled(param.Type(), called)
+	return tn, targs, called
+}
+
+// matchCalled returns the interface in scope with exactly the methods
+// in called, as found in type t. With Config.Compose, it falls back to
+// one composed of those in scope, and then with Config.Superset to the
+// closest interface with more methods.
+func (c *Checker) matchCalled(t types.Type, called map[string]string) (*types.TypeName, []types.Type) {
+	if tns := c.ifaces[funcMapString(called)]; len(tns) > 0 {
+		return tns[0], nil
+	}
+	// the last ones come from the closest packages
+	for i := len(c.generic) - 1; i >= 0; i-- {
+		if targs := c.instantiate(c.generic[i], t, called); targs != nil {
+			return c.generic[i], targs
+		}
+	}
+	if c.Compose {
+		if tn := c.compose(called); tn != nil {
+			return tn, nil
+		}
+	}
+	if c.Superset {
+		return c.closestSuperset(t, called), nil
+	}
+	return nil, nil
+}
+
+// closestSuperset returns the interface in scope with the fewest
+// methods that has all those in called and is implemented by t, as
+// long as it has fewer methods than t. It returns nil if there is none.
+func (c *Checker) closestSuperset(t types.Type, called map[string]string) *types.TypeName {
+	if _, ok := t.(*types.TypeParam); ok {
+		return nil
+	}
+	have := types.NewMethodSet(t).Len()
+	keys := make([]string, 0, len(c.ifaces))
+	for key := range c.ifaces {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var best *types.TypeName
+	bestMethods := have
+keys:
+	for _, key := range keys {
+		tn := c.ifaces[key][0]
+		iface := tn.Type().Underlying().(*types.Interface)
+		n := iface.NumMethods()
+		if n > bestMethods || n <= len(called) {
+			continue
+		}
+		if n == bestMethods && (best == nil || c.ranker.rank(tn) >= c.ranker.rank(best)) {
+			continue
+		}
+		for name := range called {
+			obj, _, _ := types.LookupFieldOrMethod(iface, false, tn.Pkg(), name)
+			if obj == nil {
+				continue keys
+			}
+		}
+		if !types.Implements(t, iface) {
+			continue
+		}
+		best, bestMethods = tn, n
+	}
+	return best
 }
 
 type varUsage struct {
@@ -56,34 +123,126 @@ type varUsage struct {
 	discard bool
 
 	assigned map[*varUsage]struct{}
+	// passed holds the params of a concrete type the variable is passed
+	// to, with Config.Interprocedural.
+	passed map[*types.Var]struct{}
+	// nilChecks holds the positions of the comparisons of the variable
+	// with nil.
+	nilChecks []token.Pos
+	// sink is the first reflection sink the variable is passed to.
+	sink string
 }
 
 type funcDecl struct {
-	astDecl *ast.FuncDecl
-	ssaFn   *ssa.Function
+	// name is that of the func, or of the func around it followed by
+	// ".funcN" for func literals.
+	name  string
+	ftype *ast.FuncType
+	sign  *types.Signature
+	lit   bool
+
+	ssaFn *ssa.Function // nil for func literals
 }
 
-// CheckArgs checks the packages specified by their import paths in
-// args.
-func CheckArgs(args []string) ([]string, error) {
-	paths := gotool.ImportPaths(args)
-	conf := loader.Config{}
-	conf.AllowErrors = true
-	rest, err := conf.FromArgs(paths, false)
+func (fd *funcDecl) exported() bool {
+	return !fd.lit && ast.IsExported(fd.name)
+}
+
+// LoadArgs loads the packages matched by the patterns in args, which
+// are handed to go/packages as-is, and builds their SSA program. Module
+// patterns such as ./... or example.com/mod/... are supported.
+func LoadArgs(args []string) ([]*packages.Package, *ssa.Program, error) {
+	pkgs, _, prog, err := LoadDependents(args, nil)
+	return pkgs, prog, err
+}
+
+// LoadDependents is like LoadArgs, but it also loads the packages
+// matched by the patterns in roots which depend on any of those in
+// args, such as the rest of a module with ./... at its root. They are
+// returned separately, to be set via Checker.Dependents.
+func LoadDependents(args, roots []string) (pkgs, dependents []*packages.Package, prog *ssa.Program, err error) {
+	for i, arg := range args {
+		if arg == "--" {
+			return nil, nil, nil, fmt.Errorf("unwanted extra args: %v", args[i+1:])
+		}
+	}
+	patterns := args
+	checked := make(map[string]bool)
+	if len(roots) > 0 {
+		// find out which of the loaded packages are to be checked
+		named, err := packages.Load(&packages.Config{Mode: packages.NeedName}, args...)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, pkg := range named {
+			checked[pkg.ID] = true
+		}
+		patterns = append(append([]string(nil), args...), roots...)
+	}
+	cfg := &packages.Config{Mode: packages.LoadAllSyntax}
+	all, err := packages.Load(cfg, patterns...)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
-	if len(rest) > 0 {
-		return nil, fmt.Errorf("unwanted extra args: %v", rest)
+	for _, pkg := range all {
+		if len(pkg.GoFiles) == 0 {
+			// e.g. a directory without Go files
+			continue
+		}
+		for _, perr := range pkg.Errors {
+			// carry on with type errors, like AllowErrors did
+			// with go/loader
+			if perr.Kind != packages.TypeError {
+				return nil, nil, nil, perr
+			}
+		}
 	}
-	lprog, err := conf.Load()
+	if len(roots) == 0 {
+		pkgs = all
+	} else {
+		for _, pkg := range all {
+			if checked[pkg.ID] {
+				pkgs = append(pkgs, pkg)
+			}
+		}
+		for _, pkg := range all {
+			if !checked[pkg.ID] && dependsOn(pkg, checked) {
+				dependents = append(dependents, pkg)
+			}
+		}
+	}
+	prog, _ = ssautil.AllPackages(all, 0)
+	prog.Build()
+	return pkgs, dependents, prog, nil
+}
+
+// dependsOn reports whether pkg imports any of the packages with the
+// given IDs, directly or not.
+func dependsOn(pkg *packages.Package, ids map[string]bool) bool {
+	found := false
+	packages.Visit([]*packages.Package{pkg}, func(imp *packages.Package) bool {
+		if imp != pkg && ids[imp.ID] {
+			found = true
+		}
+		return !found
+	}, nil)
+	return found
+}
+
+// CheckArgs checks the packages matched by the patterns in args, as
+// loaded by LoadArgs.
+func CheckArgs(args []string) ([]string, error) {
+	return checkArgs(Config{}, args)
+}
+
+func checkArgs(cfg Config, args []string) ([]string, error) {
+	pkgs, dependents, prog, err := LoadDependents(args, cfg.Dependents)
 	if err != nil {
 		return nil, err
 	}
-	prog := ssautil.CreateProgram(lprog, 0)
-	prog.Build()
-	c := new(Checker)
-	c.Program(lprog)
+	c := &Checker{Config: cfg}
+	c.Packages(pkgs)
+	c.Dependents(dependents)
 	c.ProgramSSA(prog)
 	issues, err := c.Check()
 	if err != nil {
@@ -104,94 +263,402 @@ func CheckArgs(args []string) ([]string, error) {
 	return lines, nil
 }
 
+// Config holds the options of a Checker. Its zero value gives the
+// default behavior.
+type Config struct {
+	// Synthesize makes the checker also report params whose used
+	// methods match no interface in scope, proposing the declaration
+	// of a new interface for them.
+	Synthesize bool
+
+	// Depth is how many levels of imports are searched for interfaces
+	// and func signatures, starting with the direct imports. Nil means
+	// the default of 2, zero only the package itself, and a negative
+	// value the whole import graph.
+	Depth *int
+
+	// Pkgs, if not empty, holds the only packages whose interfaces may
+	// be suggested. Each is an import path, or a path prefix ending in
+	// "/...". They are searched for in the whole import graph,
+	// regardless of Depth.
+	Pkgs []string
+
+	// NoCatalog leaves out the well-known standard library interfaces,
+	// such as io.Reader and fmt.Stringer, that are otherwise in scope
+	// even if not imported.
+	NoCatalog bool
+
+	// Compose makes the checker suggest an interface embedding two or
+	// three of those in scope, like interface{ io.Reader; io.Closer },
+	// if none has exactly the methods used. With Synthesize, it is
+	// proposed as a new declaration instead.
+	Compose bool
+
+	// Superset makes the checker suggest the interface with the fewest
+	// methods that has all those used, if none has exactly those, as
+	// long as the current type has more methods than it.
+	Superset bool
+
+	// Interprocedural makes passing a variable to a func's param count
+	// as using it as the interface that param can be narrowed to, so
+	// that whole call chains are narrowed at once. Calls into other
+	// packages only count if they are checked in the same run, and
+	// not when running as an Analyzer.
+	Interprocedural bool
+
+	// MatchSigns skips any func whose signature is the same as that of
+	// a method of an interface in scope or of a named func type, like
+	// older versions did, instead of only the methods which implement
+	// an interface in scope.
+	MatchSigns bool
+
+	// Dependents holds patterns, like ./... at the root of a module,
+	// matching packages which may depend on those being checked. Their
+	// interfaces are taken into account when deciding whether a method
+	// implements one, and so are their uses of funcs as values. They
+	// are loaded with LoadDependents. The Analyzer fails if any are
+	// set.
+	Dependents []string
+
+	// Alternatives makes the checker also list the other interfaces
+	// in scope with the same methods as each suggested one, best
+	// first.
+	Alternatives bool
+
+	// Sinks holds the funcs and methods observing the dynamic type of
+	// the values passed to them through reflection, named like
+	// "encoding/json.Marshal" or "(*encoding/gob.Encoder).Encode". The
+	// issues for params and fields reaching one are marked as such.
+	// Empty means DefaultSinks, and "none" alone means no sinks.
+	Sinks []string
+}
+
+// RegisterFlags adds a command-line flag for each option to fs.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&cfg.Synthesize, "synth", false, "propose new interfaces when no existing one matches")
+	fs.Var(depthFlag{&cfg.Depth}, "depth", "`levels` of imports to search for interfaces; 0 for none, -1 for all")
+	fs.Var((*listFlag)(&cfg.Pkgs), "pkgs", "comma-separated import paths to take interfaces from, like io,example.com/foo/...")
+	fs.BoolVar(&cfg.NoCatalog, "nocatalog", false, "don't suggest well-known std interfaces that aren't imported")
+	fs.BoolVar(&cfg.Compose, "compose", false, "suggest interfaces embedding others when none matches exactly")
+	fs.BoolVar(&cfg.Superset, "superset", false, "suggest the closest interface with more methods when none matches exactly")
+	fs.BoolVar(&cfg.Interprocedural, "interproc", false, "narrow params passed on to others that can be narrowed")
+	fs.BoolVar(&cfg.MatchSigns, "matchsigns", false, "skip all funcs with the signature of an interface method, like older versions")
+	fs.Var((*listFlag)(&cfg.Dependents), "rdeps", "comma-separated patterns of packages which may depend on those checked, like ./...")
+	fs.BoolVar(&cfg.Alternatives, "alts", false, "also list the other interfaces with the same methods")
+	fs.Var((*listFlag)(&cfg.Sinks), "sinks", "comma-separated funcs observing values through reflection, like encoding/json.Marshal; none for no sinks")
+}
+
+// listFlag is a flag.Value holding a comma-separated list.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(s string) error {
+	*l = nil
+	for _, elem := range strings.Split(s, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			*l = append(*l, elem)
+		}
+	}
+	return nil
+}
+
+// depthFlag is a flag.Value setting Config.Depth.
+type depthFlag struct{ p **int }
+
+func (d depthFlag) String() string {
+	if d.p == nil {
+		// the zero value, as created by the flag package
+		return ""
+	}
+	if *d.p == nil {
+		return strconv.Itoa(defaultDepth)
+	}
+	return strconv.Itoa(**d.p)
+}
+
+func (d depthFlag) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*d.p = &n
+	return nil
+}
+
+func (cfg *Config) depth() int {
+	if cfg.Depth == nil {
+		return defaultDepth
+	}
+	return *cfg.Depth
+}
+
 type Checker struct {
-	lprog *loader.Program
-	prog  *ssa.Program
+	Config
+
+	pkgs       []*packages.Package
+	dependents []*packages.Package
+	prog       *ssa.Program
 
 	pkgTypes
-	*loader.PackageInfo
+	pkg *types.Package
+	*types.Info
+	files []*ast.File
+
+	// results holds the results of the func whose body is being walked.
+	results *types.Tuple
 
 	funcs []*funcDecl
 
+	// ssaByPos holds the SSA funcs by the position of their name, or
+	// of the func keyword for func literals.
 	ssaByPos map[token.Pos]*ssa.Function
 
 	discardFuncs map[*types.Signature]struct{}
 
 	vars map[*types.Var]*varUsage
+
+	// synthesized holds the interfaces proposed with Synthesize, by
+	// method set.
+	synthesized map[*types.TypeName]bool
+	synthBySet  map[string]*types.TypeName
+
+	// composed holds the interfaces embedded in each one proposed with
+	// Compose, by method set.
+	composed     map[*types.TypeName][]*types.TypeName
+	composeBySet map[string]*types.TypeName
+
+	// fields holds the struct fields that may be narrowed, and
+	// fieldRefs the uses of them that were accounted for.
+	fields    map[*types.Var]*fieldDecl
+	fieldRefs map[*ast.SelectorExpr]bool
+
+	// escaped holds the funcs and methods used as values or called
+	// through interfaces anywhere in the packages being checked.
+	escaped map[*types.Func]bool
+
+	// sinks holds the names of the reflection sinks.
+	sinks map[string]bool
+
+	// diagnostics holds why suggestions were dropped, and rejected
+	// the variables they are about.
+	diagnostics []Diagnostic
+	rejected    map[*types.Var]bool
+
+	// narrowed holds the interface types that params can be declared
+	// as, found with Interprocedural. It is kept from one package to
+	// the next.
+	narrowed map[*types.Var]types.Type
 }
 
-var (
-	_ lint.Checker = (*Checker)(nil)
-	_ lint.WithSSA = (*Checker)(nil)
-)
+// Packages sets the packages to be checked. They must have been loaded
+// with their syntax and type information.
+func (c *Checker) Packages(pkgs []*packages.Package) {
+	c.pkgs = pkgs
+}
 
-func (c *Checker) Program(lprog *loader.Program) {
-	c.lprog = lprog
+// Dependents sets the packages depending on those to be checked, as
+// loaded by LoadDependents. The methods implementing their interfaces
+// and the funcs and methods they use as values are left alone.
+func (c *Checker) Dependents(pkgs []*packages.Package) {
+	c.dependents = pkgs
 }
 
 func (c *Checker) ProgramSSA(prog *ssa.Program) {
 	c.prog = prog
 }
 
-func (c *Checker) Check() ([]lint.Issue, error) {
-	var total []lint.Issue
+// Check returns the issues found in the packages, sorted by position.
+func (c *Checker) Check() ([]Issue, error) {
+	var total []Issue
 	c.ssaByPos = make(map[token.Pos]*ssa.Function)
-	wantPkg := make(map[*types.Package]bool)
-	for _, pinfo := range c.lprog.InitialPackages() {
-		wantPkg[pinfo.Pkg] = true
-	}
-	for fn := range ssautil.AllFunctions(c.prog) {
-		if fn.Pkg == nil { // builtin?
-			continue
+	for _, pkg := range c.pkgs {
+		// all declared funcs and methods, including those of
+		// unexported types
+		for _, obj := range pkg.TypesInfo.Defs {
+			fn, ok := obj.(*types.Func)
+			if !ok {
+				continue
+			}
+			ssaFn := c.prog.FuncValue(fn)
+			if ssaFn == nil || len(ssaFn.Blocks) == 0 { // abstract or stub
+				continue
+			}
+			c.addSSAFunc(ssaFn)
 		}
-		if len(fn.Blocks) == 0 { // stub
-			continue
+		// func literals in package-level var initializers
+		if ssaPkg := c.prog.Package(pkg.Types); ssaPkg != nil {
+			if init := ssaPkg.Func("init"); init != nil {
+				for _, anon := range init.AnonFuncs {
+					c.addSSAFunc(anon)
+				}
+			}
 		}
-		if !wantPkg[fn.Pkg.Pkg] { // not part of given pkgs
-			continue
+	}
+	// the funcs used as values in any of the packages or their
+	// dependents, including package-level var initializers
+	var fns []*ssa.Function
+	for _, fn := range c.ssaByPos {
+		fns = append(fns, fn)
+	}
+	for _, pkg := range c.dependents {
+		for _, obj := range pkg.TypesInfo.Defs {
+			if fn, ok := obj.(*types.Func); ok {
+				if ssaFn := c.prog.FuncValue(fn); ssaFn != nil {
+					fns = append(fns, ssaFn)
+				}
+			}
 		}
-		c.ssaByPos[fn.Pos()] = fn
 	}
-	for _, pinfo := range c.lprog.InitialPackages() {
-		pkg := pinfo.Pkg
-		c.getTypes(pkg)
-		c.PackageInfo = c.lprog.AllPackages[pkg]
+	all := append(append([]*packages.Package(nil), c.pkgs...), c.dependents...)
+	for _, pkg := range all {
+		if ssaPkg := c.prog.Package(pkg.Types); ssaPkg != nil {
+			if init := ssaPkg.Func("init"); init != nil {
+				fns = append(fns, init)
+			}
+		}
+	}
+	c.escaped = escapedFuncs(fns)
+	addInvokedMethods(c.escaped, c.prog)
+	for _, pkg := range all {
+		addBlankVarFuncs(c.escaped, pkg.Syntax, pkg.TypesInfo)
+	}
+	if c.index == nil {
+		c.index = syntaxIndex(c.pkgs)
+	}
+	c.narrowed = make(map[*types.Var]types.Type)
+	c.diagnostics = nil
+	c.rejected = make(map[*types.Var]bool)
+	for _, pkg := range depsFirst(c.pkgs) {
+		c.pkg = pkg.Types
+		c.implPkgs = c.implPkgs[:0]
+		for _, dep := range c.dependents {
+			if dependsOn(dep, map[string]bool{pkg.ID: true}) {
+				c.implPkgs = append(c.implPkgs, dep.Types)
+			}
+		}
+		c.getTypes(c.pkg, c.depth(), c.Pkgs, c.NoCatalog)
+		c.Info = pkg.TypesInfo
+		c.files = pkg.Syntax
 		total = append(total, c.checkPkg()...)
 	}
+	before := func(a, b token.Pos) bool {
+		pa, pb := c.prog.Fset.Position(a), c.prog.Fset.Position(b)
+		if pa.Filename != pb.Filename {
+			return pa.Filename < pb.Filename
+		}
+		return pa.Offset < pb.Offset
+	}
+	sort.Slice(total, func(i, j int) bool {
+		return before(total[i].Pos(), total[j].Pos())
+	})
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		return before(c.diagnostics[i].Pos, c.diagnostics[j].Pos)
+	})
 	return total, nil
 }
 
-func (c *Checker) checkPkg() []lint.Issue {
-	c.discardFuncs = make(map[*types.Signature]struct{})
-	c.vars = make(map[*types.Var]*varUsage)
-	c.funcs = c.funcs[:0]
-	findFuncs := func(node ast.Node) bool {
-		decl, ok := node.(*ast.FuncDecl)
-		if !ok {
-			return true
-		}
-		ssaFn := c.ssaByPos[decl.Name.Pos()]
-		if ssaFn == nil {
-			return true
+// depsFirst returns pkgs sorted so that each comes after those of them
+// it depends on.
+func depsFirst(pkgs []*packages.Package) []*packages.Package {
+	wanted := make(map[*packages.Package]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		wanted[pkg] = true
+	}
+	sorted := make([]*packages.Package, 0, len(pkgs))
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if wanted[pkg] {
+			sorted = append(sorted, pkg)
 		}
-		fd := &funcDecl{
-			astDecl: decl,
-			ssaFn:   ssaFn,
+	})
+	return sorted
+}
+
+// syntaxIndex returns a func to index the packages loaded along with
+// pkgs, which also finds the deprecated types in their syntax.
+func syntaxIndex(pkgs []*packages.Package) func(*types.Package) *pkgIndex {
+	byTypes := make(map[*types.Package]*packages.Package)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		byTypes[pkg.Types] = pkg
+	})
+	cache := make(map[*types.Package]*pkgIndex)
+	return func(pkg *types.Package) *pkgIndex {
+		if idx := cache[pkg]; idx != nil {
+			return idx
 		}
-		if c.funcSigns[signString(fd.ssaFn.Signature)] {
-			// implements interface
-			return true
+		idx := fromScope(pkg.Scope())
+		if p := byTypes[pkg]; p != nil {
+			idx.Deprecated = deprecatedTypes(p.Syntax)
 		}
-		c.funcs = append(c.funcs, fd)
-		ast.Walk(c, decl.Body)
-		return true
+		cache[pkg] = idx
+		return idx
+	}
+}
+
+// addSSAFunc records fn by position, along with the func literals in
+// it.
+func (c *Checker) addSSAFunc(fn *ssa.Function) {
+	c.ssaByPos[fn.Pos()] = fn
+	for _, anon := range fn.AnonFuncs {
+		c.addSSAFunc(anon)
 	}
-	for _, f := range c.Files {
-		ast.Inspect(f, findFuncs)
+}
+
+func (c *Checker) checkPkg() []Issue {
+	c.discardFuncs = make(map[*types.Signature]struct{})
+	c.vars = make(map[*types.Var]*varUsage)
+	c.synthesized = make(map[*types.TypeName]bool)
+	c.synthBySet = make(map[string]*types.TypeName)
+	c.composed = make(map[*types.TypeName][]*types.TypeName)
+	c.composeBySet = make(map[string]*types.TypeName)
+	c.funcs = c.funcs[:0]
+	c.findFields()
+	for _, f := range c.files {
+		for _, decl := range f.Decls {
+			if decl, ok := decl.(*ast.FuncDecl); ok {
+				c.addFuncDecl(decl)
+			}
+		}
 	}
+	c.addFuncLits()
 	return c.packageIssues()
 }
 
+func (c *Checker) addFuncDecl(decl *ast.FuncDecl) {
+	ssaFn := c.ssaByPos[decl.Name.Pos()]
+	if ssaFn == nil {
+		return
+	}
+	// walk the body even if the params aren't checked, as it may use
+	// struct fields
+	fn := c.Defs[decl.Name].(*types.Func)
+	sign := fn.Type().(*types.Signature)
+	c.walkBody(sign, decl.Body)
+	if c.keepsSignature(fn, sign) {
+		// implements interface
+		return
+	}
+	c.funcs = append(c.funcs, &funcDecl{
+		name:  decl.Name.Name,
+		ftype: decl.Type,
+		sign:  sign,
+		ssaFn: ssaFn,
+	})
+}
+
+// keepsSignature reports whether a func or method must keep its
+// signature, as its receiver type implements an interface in scope with
+// it. Funcs assigned to named func types are used as values, which is
+// dealt with separately. With Config.MatchSigns, any func whose
+// signature is that of a method in an interface in scope or of a named
+// func type is kept instead. fn is nil for func literals.
+func (c *Checker) keepsSignature(fn *types.Func, sign *types.Signature) bool {
+	if c.MatchSigns {
+		return c.funcSigns[signString(sign)]
+	}
+	return fn != nil && c.implements(fn)
+}
+
 func paramVarAndType(sign *types.Signature, i int) (*types.Var, types.Type) {
 	params := sign.Params()
 	extra := sign.Variadic() && i >= params.Len()-1
@@ -212,27 +679,48 @@ func paramVarAndType(sign *types.Signature, i int) (*types.Var, types.Type) {
 	}
 }
 
+// walkBody walks the body of a func with the given signature.
+func (c *Checker) walkBody(sign *types.Signature, body *ast.BlockStmt) {
+	outer := c.results
+	c.results = sign.Results()
+	ast.Walk(c, body)
+	c.results = outer
+}
+
 func (c *Checker) varUsage(e ast.Expr) *varUsage {
-	id, ok := e.(*ast.Ident)
-	if !ok {
-		return nil
-	}
-	param, ok := c.ObjectOf(id).(*types.Var)
-	if !ok {
-		// not a variable
-		return nil
+	e = ast.Unparen(e)
+	switch x := e.(type) {
+	case *ast.Ident:
+		vr, ok := c.ObjectOf(x).(*types.Var)
+		if !ok {
+			// not a variable
+			return nil
+		}
+		return c.usageOf(vr)
+	case *ast.SelectorExpr:
+		field := c.fieldOf(x)
+		if field == nil {
+			return nil
+		}
+		c.fieldRefs[x] = true
+		return c.usageOf(field)
 	}
-	if usage, e := c.vars[param]; e {
+	return nil
+}
+
+func (c *Checker) usageOf(vr *types.Var) *varUsage {
+	if usage, e := c.vars[vr]; e {
 		return usage
 	}
-	if !interesting(param.Type()) {
+	if !interesting(vr.Type()) {
 		return nil
 	}
 	usage := &varUsage{
 		calls:    make(map[string]struct{}),
 		assigned: make(map[*varUsage]struct{}),
+		passed:   make(map[*types.Var]struct{}),
 	}
-	c.vars[param] = usage
+	c.vars[vr] = usage
 	return usage
 }
 
@@ -258,7 +746,10 @@ func (c *Checker) addUsed(e ast.Expr, as types.Type) {
 }
 
 func (c *Checker) addAssign(to, from ast.Expr) {
-	pto := c.varUsage(to)
+	c.addAssignTo(c.varUsage(to), from)
+}
+
+func (c *Checker) addAssignTo(pto *varUsage, from ast.Expr) {
 	pfrom := c.varUsage(from)
 	if pto == nil || pfrom == nil {
 		// either isn't interesting
@@ -273,16 +764,44 @@ func (c *Checker) discard(e ast.Expr) {
 	}
 }
 
+// comparedWithNil records that e is compared with nil at pos. An
+// interface holding a nil pointer isn't nil itself, so the comparison
+// would change meaning if e was narrowed.
+func (c *Checker) comparedWithNil(e ast.Expr, pos token.Pos) {
+	if usage := c.varUsage(e); usage != nil {
+		usage.nilChecks = append(usage.nilChecks, pos)
+	}
+}
+
+func (c *Checker) isNil(e ast.Expr) bool {
+	return c.Types[e].IsNil()
+}
+
 func (c *Checker) comparedWith(e, with ast.Expr) {
 	if _, ok := with.(*ast.BasicLit); ok {
 		c.discard(e)
+	} else if sel, ok := e.(*ast.SelectorExpr); ok {
+		// comparing a field still works as an interface
+		c.varUsage(sel)
 	}
 }
 
 func (c *Checker) Visit(node ast.Node) ast.Visitor {
 	switch x := node.(type) {
+	case *ast.FuncLit:
+		c.walkBody(c.TypeOf(x).(*types.Signature), x.Body)
+		return nil
 	case *ast.SelectorExpr:
-		if _, ok := c.TypeOf(x.Sel).(*types.Signature); !ok {
+		sel := c.Selections[x]
+		switch {
+		case sel == nil:
+			// qualified identifier
+		case sel.Kind() == types.MethodVal:
+			// method value, or the method in a call
+			if usage := c.varUsage(x.X); usage != nil {
+				usage.calls[x.Sel.Name] = struct{}{}
+			}
+		case sel.Kind() == types.FieldVal:
 			c.discard(x.X)
 		}
 	case *ast.StarExpr:
@@ -291,37 +810,106 @@ func (c *Checker) Visit(node ast.Node) ast.Visitor {
 		c.discard(x.X)
 	case *ast.IndexExpr:
 		c.discard(x.X)
+		if m, ok := c.TypeOf(x.X).Underlying().(*types.Map); ok {
+			c.addUsed(x.Index, m.Key())
+		}
+	case *ast.SliceExpr:
+		c.discard(x.X)
 	case *ast.IncDecStmt:
 		c.discard(x.X)
+	case *ast.ReturnStmt:
+		if c.results == nil || len(x.Results) != c.results.Len() {
+			// naked, or a call returning multiple values
+			break
+		}
+		for i, res := range x.Results {
+			c.addUsed(res, c.results.At(i).Type())
+		}
+	case *ast.SendStmt:
+		if ch, ok := c.TypeOf(x.Chan).Underlying().(*types.Chan); ok {
+			c.addUsed(x.Value, ch.Elem())
+		}
+	case *ast.RangeStmt:
+		c.discard(x.X)
 	case *ast.BinaryExpr:
 		switch x.Op {
 		case token.EQL, token.NEQ:
+			if c.isNil(x.Y) {
+				c.comparedWithNil(x.X, x.Pos())
+			} else if c.isNil(x.X) {
+				c.comparedWithNil(x.Y, x.Pos())
+			}
 			c.comparedWith(x.X, x.Y)
 			c.comparedWith(x.Y, x.X)
 		default:
 			c.discard(x.X)
 			c.discard(x.Y)
 		}
+	case *ast.SwitchStmt:
+		if x.Tag == nil {
+			break
+		}
+		for _, stmt := range x.Body.List {
+			for _, e := range stmt.(*ast.CaseClause).List {
+				if c.isNil(e) {
+					c.comparedWithNil(x.Tag, e.Pos())
+				}
+			}
+		}
 	case *ast.ValueSpec:
-		for _, val := range x.Values {
+		for i, val := range x.Values {
+			if x.Type == nil && len(x.Names) == len(x.Values) {
+				// the variable is declared with the type of val
+				c.addAssign(x.Names[i], val)
+				continue
+			}
 			c.addUsed(val, c.TypeOf(x.Type))
 		}
 	case *ast.AssignStmt:
+		if x.Tok != token.ASSIGN && x.Tok != token.DEFINE {
+			// an assignment operation like +=
+			c.discard(x.Lhs[0])
+			c.discard(x.Rhs[0])
+			break
+		}
+		for _, left := range x.Lhs {
+			// assigning to a field works as an interface too
+			c.fieldUsage(left)
+		}
 		for i, val := range x.Rhs {
 			left := x.Lhs[i]
-			if x.Tok == token.ASSIGN {
+			if x.Tok == token.ASSIGN && c.fieldUsage(left) == nil {
 				c.addUsed(val, c.TypeOf(left))
 			}
 			c.addAssign(left, val)
 		}
 	case *ast.CompositeLit:
+		st, _ := c.TypeOf(x).Underlying().(*types.Struct)
+		m, _ := c.TypeOf(x).Underlying().(*types.Map)
 		for i, e := range x.Elts {
 			switch y := e.(type) {
 			case *ast.KeyValueExpr:
-				c.addUsed(y.Key, c.TypeOf(y.Value))
-				c.addUsed(y.Value, c.TypeOf(y.Key))
-			case *ast.Ident:
-				c.addUsed(y, compositeIdentType(c.TypeOf(x), i))
+				if field := c.keyField(st, y.Key); field != nil {
+					c.addAssignTo(c.usageOf(field), y.Value)
+					continue
+				}
+				switch {
+				case m != nil:
+					c.addUsed(y.Key, m.Key())
+					c.addUsed(y.Value, m.Elem())
+				case st == nil:
+					// indexed array or slice element
+					c.addUsed(y.Value, compositeIdentType(c.TypeOf(x), 0))
+				default:
+					c.addUsed(y.Key, c.TypeOf(y.Value))
+					c.addUsed(y.Value, c.TypeOf(y.Key))
+				}
+			case *ast.Ident, *ast.SelectorExpr:
+				if st != nil && c.fields[st.Field(i)] != nil {
+					c.addAssignTo(c.usageOf(st.Field(i)), e)
+					continue
+				}
+				c.addUsed(e, compositeIdentType(c.TypeOf(x), i))
 			}
 		}
 	case *ast.CallExpr:
@@ -358,10 +946,24 @@ func (c *Checker) onMethodCall(ce *ast.CallExpr, sign *types.Signature) {
 		// Don't if this is a parameter being re-used as itself
 		// in a recursive call
 		if id, ok := e.(*ast.Ident); ok {
-			if paramObj == c.ObjectOf(id) {
+			if paramObj != nil && paramObj.Origin() == c.ObjectOf(id) {
 				continue
 			}
 		}
+		if sink := c.reachedSink(ce, i); sink != "" {
+			if usage := c.varUsage(e); usage != nil && usage.sink == "" {
+				usage.sink = sink
+			}
+		}
+		if c.inferredArg(ce, i) {
+			// its type must stay the same, as the callee's
+			// instantiation depends on it
+			c.discard(e)
+			continue
+		}
+		if c.Interprocedural && paramObj != nil && c.passTo(e, paramObj.Origin()) {
+			continue
+		}
 		c.addUsed(e, t)
 	}
 	sel, ok := ce.Fun.(*ast.SelectorExpr)
@@ -375,13 +977,13 @@ func (c *Checker) onMethodCall(ce *ast.CallExpr, sign *types.Signature) {
 }
 
 func (fd *funcDecl) paramGroups() [][]*types.Var {
-	astList := fd.astDecl.Type.Params.List
+	astList := fd.ftype.Params.List
 	groups := make([][]*types.Var, len(astList))
 	signIndex := 0
 	for i, field := range astList {
 		group := make([]*types.Var, len(field.Names))
 		for j := range field.Names {
-			group[j] = fd.ssaFn.Signature.Params().At(signIndex)
+			group[j] = fd.sign.Params().At(signIndex)
 			signIndex++
 		}
 		groups[i] = group
@@ -389,44 +991,197 @@ func (fd *funcDecl) paramGroups() [][]*types.Var {
 	return groups
 }
 
-func (c *Checker) packageIssues() []lint.Issue {
-	var issues []lint.Issue
+func (c *Checker) packageIssues() []Issue {
+	for _, fd := range c.funcs {
+		c.ssaUsage(fd)
+	}
+	if c.Interprocedural {
+		c.narrowCalls()
+	}
+	issues := c.fieldIssues()
 	for _, fd := range c.funcs {
-		if _, e := c.discardFuncs[fd.ssaFn.Signature]; e {
+		if c.funcEscapes(fd) {
 			continue
 		}
-		for _, group := range fd.paramGroups() {
-			issues = append(issues, c.groupIssues(fd, group)...)
+		fields := fd.ftype.Params.List
+		for i, group := range fd.paramGroups() {
+			issues = append(issues, c.groupIssues(fd, fields[i], group)...)
 		}
+		issues = append(issues, c.typeParamIssues(fd)...)
 	}
 	return issues
 }
 
+// funcEscapes reports whether fd is used as a value, so that its
+// signature must stay the same.
+func (c *Checker) funcEscapes(fd *funcDecl) bool {
+	if _, e := c.discardFuncs[fd.sign]; e {
+		return true
+	}
+	if fd.ssaFn == nil {
+		return false
+	}
+	obj, ok := fd.ssaFn.Object().(*types.Func)
+	return ok && c.escaped[obj]
+}
+
+// Issue is a func parameter or struct field that could be declared
+// with an interface type instead.
 type Issue struct {
 	pos token.Pos
 	msg string
+
+	// Func is the name of the func or method declaring Param, like
+	// "Outer.func1" for the first func literal in Outer. It is empty
+	// for struct fields.
+	Func string
+	// Struct is the name of the struct type declaring Param, if it's a
+	// field.
+	Struct string
+	// Param is the parameter or field whose type could be narrowed. It
+	// is nil for type parameters.
+	Param *types.Var
+	// TypeParam is the type parameter whose constraint could be
+	// narrowed, if any.
+	TypeParam *types.TypeParam
+	// TypeExpr is the type expression of Param in the source. It is
+	// shared by all the params in a group, like "a, b *T".
+	TypeExpr ast.Expr
+	// Iface is the suggested interface type. It is nil if an anonymous
+	// interface embedding Embeds is suggested.
+	Iface *types.TypeName
+	// IfaceArgs holds the type arguments to instantiate Iface with, if
+	// it's generic.
+	IfaceArgs []types.Type
+	// Embeds holds the interfaces embedded in the suggested one, if
+	// proposed with Config.Compose.
+	Embeds []*types.TypeName
+	// Alternatives holds the other interfaces in scope with the same
+	// methods as Iface, best first. It is only set with
+	// Config.Alternatives.
+	Alternatives []*types.TypeName
+	// Superset is set if Iface has more methods than those used, as
+	// suggested with Config.Superset.
+	Superset bool
+	// Sink is the reflection sink Param is passed to, if any, as
+	// configured with Config.Sinks. Narrowing it doesn't change its
+	// dynamic type, but the code may rely on how it's observed.
+	Sink string
+	// Decl is the source of the declaration of Iface if it doesn't
+	// exist yet, as proposed with Config.Synthesize.
+	Decl string
+	// Methods holds the sorted names of the methods used on Param.
+	Methods []string
 }
 
 func (i Issue) Pos() token.Pos  { return i.pos }
 func (i Issue) Message() string { return i.msg }
 
-func (c *Checker) groupIssues(fd *funcDecl, group []*types.Var) []lint.Issue {
-	var issues []lint.Issue
-	for _, param := range group {
+// obj returns the param, field or type param the issue is about.
+func (i Issue) obj() types.Object {
+	if i.TypeParam != nil {
+		return i.TypeParam.Obj()
+	}
+	return i.Param
+}
+
+func (c *Checker) groupIssues(fd *funcDecl, field *ast.Field, group []*types.Var) []Issue {
+	var issues []Issue
+	for _, pt := range c.groupNewTypes(fd, group) {
+		issue := c.newIssue(pt.param.Name(), pt.param.Pos(), pt.iface, pt.targs, pt.called)
+		issue.Func = fd.name
+		issue.Param = pt.param
+		issue.TypeExpr = field.Type
+		markSink(&issue, pt.usage)
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// paramType is the interface a param can be declared as.
+type paramType struct {
+	param *types.Var
+	usage *varUsage
+
+	iface  *types.TypeName
+	targs  []types.Type
+	called map[string]string
+}
+
+// groupNewTypes returns the interfaces that the params in a group, as
+// in "a, b *T", can be declared as. As they share a type expression,
+// it returns nil unless all of them can be narrowed.
+func (c *Checker) groupNewTypes(fd *funcDecl, group []*types.Var) []paramType {
+	pts := make([]paramType, len(group))
+	for i, param := range group {
 		usage := c.vars[param]
 		if usage == nil {
 			return nil
 		}
-		newType := c.paramNewType(fd.astDecl.Name.Name, param, usage)
-		if newType == "" {
+		iface, targs, called := c.paramNewType(fd, param, usage)
+		if iface == nil {
 			return nil
 		}
-		issues = append(issues, Issue{
-			pos: param.Pos(),
-			msg: fmt.Sprintf("%s can be %s", param.Name(), newType),
-		})
+		pts[i] = paramType{param, usage, iface, targs, called}
+	}
+	return pts
+}
+
+// newIssue returns the issue suggesting iface, instantiated with targs
+// if generic, for the object declared at pos, given the methods called
+// on it.
+func (c *Checker) newIssue(name string, pos token.Pos, iface *types.TypeName, targs []types.Type, called map[string]string) Issue {
+	methods := make([]string, 0, len(called))
+	for name := range called {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+	if embeds := c.composed[iface]; embeds != nil && !c.synthesized[iface] {
+		return Issue{
+			pos:     pos,
+			msg:     fmt.Sprintf("%s can be %s", name, c.composedString(embeds)),
+			Embeds:  embeds,
+			Methods: methods,
+		}
+	}
+	msg := fmt.Sprintf("%s can be %s", name, c.typeName(iface, targs))
+	key := funcMapString(called)
+	superset := false
+	if targs == nil && !c.synthesized[iface] {
+		if have := funcMapString(typeFuncMap(iface.Type())); have != key {
+			key, superset = have, true
+			msg += ", the closest interface with the methods used"
+		}
+	}
+	var alts []*types.TypeName
+	if c.Alternatives && targs == nil {
+		if tns := c.ifaces[key]; len(tns) > 1 && tns[0] == iface {
+			alts = tns[1:]
+			names := make([]string, len(alts))
+			for i, alt := range alts {
+				names[i] = c.typeName(alt, nil)
+			}
+			msg += fmt.Sprintf(" (alternatives: %s)", strings.Join(names, ", "))
+		}
+	}
+	var decl string
+	if c.synthesized[iface] {
+		decl = ifaceDecl(iface)
+		msg = fmt.Sprintf("%s can be %s, a new interface to declare in %s: %s",
+			name, iface.Name(), iface.Pkg().Path(),
+			types.TypeString(iface.Type().Underlying(), types.RelativeTo(iface.Pkg())))
+	}
+	return Issue{
+		pos:          pos,
+		msg:          msg,
+		Iface:        iface,
+		IfaceArgs:    targs,
+		Embeds:       c.composed[iface],
+		Alternatives: alts,
+		Superset:     superset,
+		Decl:         decl,
+		Methods:      methods,
 	}
-	return issues
 }
 
 func willAddAllocation(t types.Type) bool {
@@ -437,26 +1192,54 @@ func willAddAllocation(t types.Type) bool {
 	return true
 }
 
-func (c *Checker) paramNewType(funcName string, param *types.Var, usage *varUsage) string {
+// typeName returns the name of tn as written in messages, which is
+// qualified by its package path unless it's from the checked package,
+// followed by its type arguments if any.
+func (c *Checker) typeName(tn *types.TypeName, targs []types.Type) string {
+	name := tn.Name()
+	if tn.Pkg() != nil && tn.Pkg() != c.pkg {
+		name = tn.Pkg().Path() + "." + name
+	}
+	if len(targs) > 0 {
+		name += typeArgsString(targs, types.RelativeTo(c.pkg))
+	}
+	return name
+}
+
+func (c *Checker) paramNewType(fd *funcDecl, param *types.Var, usage *varUsage) (*types.TypeName, []types.Type, map[string]string) {
 	t := param.Type()
-	if !ast.IsExported(funcName) && willAddAllocation(t) {
-		return ""
+	if !fd.exported() && willAddAllocation(t) {
+		return nil, nil, nil
 	}
 	if named := typeNamed(t); named != nil {
 		tname := named.Obj().Name()
 		vname := param.Name()
-		if mentionsName(funcName, tname) || mentionsName(funcName, vname) {
-			return ""
+		if mentionsName(fd.name, tname) || mentionsName(fd.name, vname) {
+			return nil, nil, nil
 		}
 	}
-	ifname, iftype := c.interfaceMatching(param, usage)
-	if ifname == "" {
-		return ""
+	return c.newType(param, usage)
+}
+
+// newType returns the interface that vr could be declared as, given its
+// usage, along with its type arguments if generic and the methods
+// called on vr.
+func (c *Checker) newType(vr *types.Var, usage *varUsage) (*types.TypeName, []types.Type, map[string]string) {
+	iface, targs, called := c.interfaceMatching(vr, usage)
+	if iface == nil && c.Synthesize && len(called) > 0 {
+		iface = c.synthesize(vr, called)
+	}
+	if iface == nil {
+		return nil, nil, nil
 	}
+	t := vr.Type()
 	if types.IsInterface(t.Underlying()) {
-		if have := funcMapString(typeFuncMap(t)); have == iftype {
-			return ""
+		if have := funcMapString(typeFuncMap(t)); have == funcMapString(called) {
+			return nil, nil, nil
 		}
 	}
-	return ifname
+	if !c.satisfies(vr, iface, targs) || !c.nilSafe(vr, usage, iface, targs) {
+		return nil, nil, nil
+	}
+	return iface, targs, called
 }
@@ -0,0 +1,128 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// maxComposed is the most interfaces a suggestion may be composed of.
+const maxComposed = 3
+
+// compose returns an interface embedding two or three of those in
+// scope whose methods are, put together, exactly those in called. It
+// is anonymous, unless Synthesize is set, in which case it is proposed
+// as a new declaration. It returns nil if there is none.
+func (c *Checker) compose(called map[string]string) *types.TypeName {
+	key := funcMapString(called)
+	if tn, e := c.composeBySet[key]; e {
+		return tn
+	}
+	embeds := c.composeParts(called)
+	var tn *types.TypeName
+	if embeds != nil {
+		etypes := make([]types.Type, len(embeds))
+		for i, embed := range embeds {
+			etypes[i] = embed.Type()
+		}
+		iface := types.NewInterfaceType(nil, etypes).Complete()
+		if c.Synthesize {
+			names := make([]string, 0, len(called))
+			for name := range called {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			tn = types.NewTypeName(token.NoPos, c.pkg, c.unusedName(c.pkg, ifaceNameFor(names)), nil)
+			types.NewNamed(tn, iface, nil)
+			c.synthesized[tn] = true
+		} else {
+			tn = types.NewTypeName(token.NoPos, nil, "", iface)
+		}
+		c.composed[tn] = embeds
+	}
+	c.composeBySet[key] = tn
+	return tn
+}
+
+// composeParts returns the fewest interfaces in scope, up to
+// maxComposed, whose methods are exactly those in called. Among those,
+// it prefers the ones overlapping the least and then the best ranked.
+func (c *Checker) composeParts(called map[string]string) []*types.TypeName {
+	type part struct {
+		tn      *types.TypeName
+		methods map[string]string
+	}
+	var parts []part
+	for _, tns := range c.ifaces {
+		tn := tns[0]
+		methods := typeFuncMap(tn.Type())
+		if len(methods) >= len(called) {
+			continue
+		}
+		subset := true
+		for name, sign := range methods {
+			if called[name] != sign {
+				subset = false
+				break
+			}
+		}
+		if subset {
+			parts = append(parts, part{tn, methods})
+		}
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		ri, rj := c.ranker.rank(parts[i].tn), c.ranker.rank(parts[j].tn)
+		if ri != rj {
+			return ri < rj
+		}
+		return c.typeName(parts[i].tn, nil) < c.typeName(parts[j].tn, nil)
+	})
+	var best []*types.TypeName
+	bestTotal := 0
+	var try func(limit, start int, chosen []*types.TypeName, covered map[string]bool, total int)
+	try = func(limit, start int, chosen []*types.TypeName, covered map[string]bool, total int) {
+		if len(covered) == len(called) {
+			// parts are tried best ranked first, so only replace a
+			// previous find if it overlaps less
+			if best == nil || total < bestTotal {
+				best = append([]*types.TypeName(nil), chosen...)
+				bestTotal = total
+			}
+			return
+		}
+		if len(chosen) == limit {
+			return
+		}
+		for i := start; i < len(parts); i++ {
+			added := make(map[string]bool, len(called))
+			for name := range covered {
+				added[name] = true
+			}
+			for name := range parts[i].methods {
+				added[name] = true
+			}
+			if len(added) > len(covered) {
+				try(limit, i+1, append(chosen, parts[i].tn), added, total+len(parts[i].methods))
+			}
+		}
+	}
+	// as few parts as possible
+	for limit := 2; limit <= maxComposed && best == nil; limit++ {
+		try(limit, 0, nil, map[string]bool{}, 0)
+	}
+	return best
+}
+
+// composedString returns how the anonymous interface embedding embeds
+// is written in messages.
+func (c *Checker) composedString(embeds []*types.TypeName) string {
+	names := make([]string, len(embeds))
+	for i, embed := range embeds {
+		names[i] = c.typeName(embed, nil)
+	}
+	return "interface{ " + strings.Join(names, "; ") + " }"
+}
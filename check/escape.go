@@ -0,0 +1,110 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/ssa"
+)
+
+// escapedFuncs returns the funcs and methods used as values anywhere in
+// the given SSA funcs or the func literals within them, like callbacks
+// and method values, which are closures over a wrapper of the method.
+// Their signatures can't change without breaking the code using them,
+// even if it's in another package.
+//
+// Any func called through a func value must have been used as a value
+// first, so this covers the dynamic calls of func values that a call
+// graph would find, without CHA's assumption that such a call may reach
+// any func with the same signature. Calls through interfaces are added
+// by addInvokedMethods.
+func escapedFuncs(fns []*ssa.Function) map[*types.Func]bool {
+	escaped := make(map[*types.Func]bool)
+	var rands []*ssa.Value
+	var visit func(fn *ssa.Function)
+	visit = func(fn *ssa.Function) {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				rands = instr.Operands(rands[:0])
+				if call, ok := instr.(ssa.CallInstruction); ok && !call.Common().IsInvoke() {
+					// the func being called comes first
+					rands = rands[1:]
+				}
+				for _, rand := range rands {
+					g, ok := (*rand).(*ssa.Function)
+					if !ok {
+						continue
+					}
+					if obj, ok := g.Object().(*types.Func); ok {
+						escaped[obj.Origin()] = true
+					}
+				}
+			}
+		}
+		for _, anon := range fn.AnonFuncs {
+			visit(anon)
+		}
+	}
+	for _, fn := range fns {
+		visit(fn)
+	}
+	return escaped
+}
+
+// addInvokedMethods adds to escaped the methods which may be called
+// through an interface anywhere in prog, as found by its CHA call graph.
+// Their types need not be used as interfaces in the packages declaring
+// them, like when only a dependent package declares the interface.
+func addInvokedMethods(escaped map[*types.Func]bool, prog *ssa.Program) {
+	cg := cha.CallGraph(prog)
+	for _, node := range cg.Nodes {
+		for _, edge := range node.Out {
+			if edge.Site == nil || !edge.Site.Common().IsInvoke() {
+				continue
+			}
+			if obj, ok := edge.Callee.Func.Object().(*types.Func); ok {
+				escaped[obj.Origin()] = true
+			}
+		}
+	}
+}
+
+// addBlankVarFuncs adds to escaped the funcs assigned to package-level
+// vars named _, like "var _ MyFunc = Impl", which are often used to
+// check that a func has a signature. Their values don't make it to the
+// SSA form.
+func addBlankVarFuncs(escaped map[*types.Func]bool, files []*ast.File, info *types.Info) {
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, val := range vs.Values {
+					if i >= len(vs.Names) || vs.Names[i].Name != "_" {
+						continue
+					}
+					var id *ast.Ident
+					switch x := ast.Unparen(val).(type) {
+					case *ast.Ident:
+						id = x
+					case *ast.SelectorExpr:
+						id = x.Sel
+					default:
+						continue
+					}
+					if fn, ok := info.Uses[id].(*types.Func); ok {
+						escaped[fn.Origin()] = true
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+)
+
+// fieldDecl is a struct field that could be declared with an interface
+// type.
+type fieldDecl struct {
+	field *ast.Field
+	strct *types.TypeName
+}
+
+// findFields records the fields of the package's struct types that may
+// be narrowed. Exported fields are left out, since their uses in other
+// packages are unknown, as are embedded fields, which promote their
+// methods.
+func (c *Checker) findFields() {
+	c.fields = make(map[*types.Var]*fieldDecl)
+	c.fieldRefs = make(map[*ast.SelectorExpr]bool)
+	for _, f := range c.files {
+		ast.Inspect(f, func(node ast.Node) bool {
+			spec, ok := node.(*ast.TypeSpec)
+			if !ok || spec.TypeParams != nil || spec.Assign.IsValid() {
+				return true
+			}
+			st, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			tn, ok := c.Defs[spec.Name].(*types.TypeName)
+			if !ok {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				for _, name := range field.Names {
+					vr, ok := c.Defs[name].(*types.Var)
+					if !ok || vr.Exported() || !interesting(vr.Type()) {
+						continue
+					}
+					c.fields[vr] = &fieldDecl{field: field, strct: tn}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// fieldOf returns the field selected by sel, if it may be narrowed.
+func (c *Checker) fieldOf(sel *ast.SelectorExpr) *types.Var {
+	s := c.Selections[sel]
+	if s == nil || s.Kind() != types.FieldVal {
+		return nil
+	}
+	vr, _ := s.Obj().(*types.Var)
+	if c.fields[vr] == nil {
+		return nil
+	}
+	return vr
+}
+
+// fieldUsage returns the usage of the field selected by e, if it may be
+// narrowed.
+func (c *Checker) fieldUsage(e ast.Expr) *varUsage {
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok || c.fieldOf(sel) == nil {
+		return nil
+	}
+	return c.varUsage(sel)
+}
+
+// keyField returns the field set by a key in a composite literal of
+// type st, if it may be narrowed.
+func (c *Checker) keyField(st *types.Struct, key ast.Expr) *types.Var {
+	id, ok := key.(*ast.Ident)
+	if st == nil || !ok {
+		return nil
+	}
+	vr, _ := c.ObjectOf(id).(*types.Var)
+	if c.fields[vr] == nil {
+		return nil
+	}
+	return vr
+}
+
+// fieldIssues returns the fields that could be declared with an
+// interface type. Those that can't are marked as discarded, so that the
+// values assigned to them aren't narrowed either.
+func (c *Checker) fieldIssues() []Issue {
+	// any use of a field that wasn't accounted for, like in a func
+	// that wasn't walked, may need its current type
+	for _, f := range c.files {
+		ast.Inspect(f, func(node ast.Node) bool {
+			sel, ok := node.(*ast.SelectorExpr)
+			if !ok || c.fieldRefs[sel] {
+				return true
+			}
+			if field := c.fieldOf(sel); field != nil {
+				c.usageOf(field).discard = true
+			}
+			return true
+		})
+	}
+	fields := make([]*types.Var, 0, len(c.fields))
+	for vr := range c.fields {
+		fields = append(fields, vr)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Pos() < fields[j].Pos()
+	})
+	var issues []Issue
+	for _, vr := range fields {
+		usage := c.vars[vr]
+		if usage == nil {
+			// never used
+			continue
+		}
+		fd := c.fields[vr]
+		iface, targs, called := c.fieldNewType(fd.strct.Name(), vr, usage)
+		if iface == nil {
+			usage.discard = true
+			continue
+		}
+		issue := c.newIssue(vr.Name(), vr.Pos(), iface, targs, called)
+		issue.Param = vr
+		issue.Struct = fd.strct.Name()
+		issue.TypeExpr = fd.field.Type
+		markSink(&issue, usage)
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+func (c *Checker) fieldNewType(structName string, field *types.Var, usage *varUsage) (*types.TypeName, []types.Type, map[string]string) {
+	t := field.Type()
+	if willAddAllocation(t) {
+		return nil, nil, nil
+	}
+	if named := typeNamed(t); named != nil {
+		if mentionsName(structName, named.Obj().Name()) {
+			return nil, nil, nil
+		}
+	}
+	return c.newType(field, usage)
+}
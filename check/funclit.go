@@ -0,0 +1,196 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// funcLit is a func literal found in the package. It's named like the gc
+// compiler names its symbol when it's not inlined, without the package
+// path: "Outer.func1" for the first literal in Outer, "Outer.func1.1"
+// for the first one inside that, "(*T).M.func1" in a method, and
+// "init.func1" in a package-level var initializer.
+type funcLit struct {
+	lit  *ast.FuncLit
+	name string
+
+	// bound is the variable holding the literal, if any.
+	bound *types.Var
+	// walked is whether its body was walked with the func around it.
+	walked bool
+}
+
+// addFuncLits records the func literals whose params are to be checked.
+// As their signature can't be changed when they are used as a value,
+// only literals that are called directly, or that are assigned to a
+// new variable which is only ever called, are considered. Exported
+// package-level variables are left out, as other packages may use them.
+func (c *Checker) addFuncLits() {
+	var lits []funcLit
+	// vars which are used other than by calling them
+	notCalled := make(map[*types.Var]bool)
+	globLits := 0
+	for _, f := range c.files {
+		for _, decl := range f.Decls {
+			outer, count := "init", &globLits
+			funcDecl, walked := decl.(*ast.FuncDecl)
+			if walked {
+				outer, count = declName(funcDecl), new(int)
+				if c.ssaByPos[funcDecl.Name.Pos()] == nil {
+					continue
+				}
+			}
+			// the names and literal counts of the literals in decl
+			litNames := make(map[*ast.FuncLit]string)
+			litCounts := make(map[*ast.FuncLit]int)
+			var stack []ast.Node
+			ast.Inspect(decl, func(node ast.Node) bool {
+				if node == nil {
+					stack = stack[:len(stack)-1]
+					return true
+				}
+				var parent ast.Node
+				var enclosing *ast.FuncLit
+				if len(stack) > 0 {
+					parent = stack[len(stack)-1]
+				}
+				for i := len(stack) - 1; i >= 0 && enclosing == nil; i-- {
+					enclosing, _ = stack[i].(*ast.FuncLit)
+				}
+				stack = append(stack, node)
+				switch x := node.(type) {
+				case *ast.Ident:
+					vr, ok := c.Uses[x].(*types.Var)
+					if !ok {
+						break
+					}
+					if call, ok := parent.(*ast.CallExpr); !ok || call.Fun != x {
+						notCalled[vr] = true
+					}
+				case *ast.FuncLit:
+					if enclosing != nil {
+						litCounts[enclosing]++
+						litNames[x] = fmt.Sprintf("%s.%d", litNames[enclosing], litCounts[enclosing])
+					} else {
+						*count++
+						litNames[x] = fmt.Sprintf("%s.func%d", outer, *count)
+					}
+					if bound, ok := c.litBinding(x, parent); ok {
+						lits = append(lits, funcLit{
+							lit:    x,
+							name:   litNames[x],
+							bound:  bound,
+							walked: walked,
+						})
+					}
+				}
+				return true
+			})
+		}
+	}
+	for _, fl := range lits {
+		if fl.bound != nil && notCalled[fl.bound] {
+			continue
+		}
+		sign := c.TypeOf(fl.lit).(*types.Signature)
+		if !fl.walked {
+			c.walkBody(sign, fl.lit.Body)
+		}
+		if c.keepsSignature(nil, sign) {
+			// implements interface
+			continue
+		}
+		c.funcs = append(c.funcs, &funcDecl{
+			name:  fl.name,
+			ftype: fl.lit.Type,
+			sign:  sign,
+			lit:   true,
+		})
+	}
+}
+
+// declName returns the name of a func or method like the gc compiler
+// names its symbol, without the package path, such as "(*T).M" or
+// "List[...].Len".
+func declName(decl *ast.FuncDecl) string {
+	name := decl.Name.Name
+	if decl.Type.TypeParams != nil {
+		name += "[...]"
+	}
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return name
+	}
+	typ := decl.Recv.List[0].Type
+	star, ok := typ.(*ast.StarExpr)
+	if ok {
+		typ = star.X
+	}
+	generic := true
+	switch x := typ.(type) {
+	case *ast.IndexExpr:
+		typ = x.X
+	case *ast.IndexListExpr:
+		typ = x.X
+	default:
+		generic = false
+	}
+	recv := types.ExprString(typ)
+	if generic {
+		recv += "[...]"
+	}
+	if star != nil {
+		recv = "(*" + recv + ")"
+	}
+	return recv + "." + name
+}
+
+// litBinding returns the variable a func literal is assigned to when
+// it's declared, given the node around it. It reports false if the
+// literal is used in any other way than that or by calling it.
+func (c *Checker) litBinding(lit *ast.FuncLit, parent ast.Node) (*types.Var, bool) {
+	var lhs []ast.Expr
+	var rhs []ast.Expr
+	switch x := parent.(type) {
+	case *ast.CallExpr:
+		return nil, x.Fun == lit
+	case *ast.AssignStmt:
+		if x.Tok != token.DEFINE {
+			return nil, false
+		}
+		lhs, rhs = x.Lhs, x.Rhs
+	case *ast.ValueSpec:
+		if x.Type != nil {
+			return nil, false
+		}
+		for _, name := range x.Names {
+			lhs = append(lhs, name)
+		}
+		rhs = x.Values
+	default:
+		return nil, false
+	}
+	if len(lhs) != len(rhs) {
+		return nil, false
+	}
+	for i, e := range rhs {
+		if e != lit {
+			continue
+		}
+		id, ok := lhs[i].(*ast.Ident)
+		if !ok {
+			return nil, false
+		}
+		vr, ok := c.Defs[id].(*types.Var)
+		if ok && vr.Exported() && vr.Parent() == vr.Pkg().Scope() {
+			// other packages may use it in any way
+			return nil, false
+		}
+		return vr, ok
+	}
+	return nil, false
+}
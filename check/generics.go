@@ -0,0 +1,378 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"bytes"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// instantiate returns the type arguments for the generic interface tn
+// such that its methods are exactly those in called, as found in type
+// t. It returns nil if there are none.
+func (c *Checker) instantiate(tn *types.TypeName, t types.Type, called map[string]string) []types.Type {
+	named, ok := tn.Type().(*types.Named)
+	if !ok {
+		return nil
+	}
+	iface, ok := named.Underlying().(*types.Interface)
+	if !ok || iface.NumMethods() != len(called) {
+		return nil
+	}
+	tparams := named.TypeParams()
+	bound := make(map[*types.TypeParam]types.Type, tparams.Len())
+	for i := 0; i < tparams.Len(); i++ {
+		bound[tparams.At(i)] = nil
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		if _, e := called[m.Name()]; !e {
+			return nil
+		}
+		obj, _, _ := types.LookupFieldOrMethod(t, true, tn.Pkg(), m.Name())
+		fn, ok := obj.(*types.Func)
+		if !ok || !bindTypes(bound, m.Type(), fn.Type()) {
+			return nil
+		}
+	}
+	targs := make([]types.Type, tparams.Len())
+	for i := range targs {
+		if targs[i] = bound[tparams.At(i)]; targs[i] == nil {
+			// not used in any method
+			return nil
+		}
+	}
+	inst, err := types.Instantiate(nil, named, targs, true)
+	if err != nil {
+		return nil
+	}
+	if funcMapString(typeFuncMap(inst)) != funcMapString(called) {
+		return nil
+	}
+	return targs
+}
+
+// bindTypes matches a type mentioning the type params in bound with an
+// actual type, recording what each of them stands for. It reports
+// false if the types can't match.
+func bindTypes(bound map[*types.TypeParam]types.Type, pattern, actual types.Type) bool {
+	pattern, actual = types.Unalias(pattern), types.Unalias(actual)
+	switch p := pattern.(type) {
+	case *types.TypeParam:
+		b, ok := bound[p]
+		if !ok {
+			break
+		}
+		if b == nil {
+			bound[p] = actual
+			return true
+		}
+		return types.Identical(b, actual)
+	case *types.Pointer:
+		a, ok := actual.(*types.Pointer)
+		return ok && bindTypes(bound, p.Elem(), a.Elem())
+	case *types.Slice:
+		a, ok := actual.(*types.Slice)
+		return ok && bindTypes(bound, p.Elem(), a.Elem())
+	case *types.Array:
+		a, ok := actual.(*types.Array)
+		return ok && p.Len() == a.Len() && bindTypes(bound, p.Elem(), a.Elem())
+	case *types.Chan:
+		a, ok := actual.(*types.Chan)
+		return ok && p.Dir() == a.Dir() && bindTypes(bound, p.Elem(), a.Elem())
+	case *types.Map:
+		a, ok := actual.(*types.Map)
+		return ok && bindTypes(bound, p.Key(), a.Key()) &&
+			bindTypes(bound, p.Elem(), a.Elem())
+	case *types.Signature:
+		a, ok := actual.(*types.Signature)
+		return ok && p.Variadic() == a.Variadic() &&
+			bindTypes(bound, p.Params(), a.Params()) &&
+			bindTypes(bound, p.Results(), a.Results())
+	case *types.Tuple:
+		a, ok := actual.(*types.Tuple)
+		if !ok || p.Len() != a.Len() {
+			return false
+		}
+		for i := 0; i < p.Len(); i++ {
+			if !bindTypes(bound, p.At(i).Type(), a.At(i).Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Named:
+		a, ok := actual.(*types.Named)
+		if !ok || p.Origin() != a.Origin() {
+			return false
+		}
+		pargs, aargs := p.TypeArgs(), a.TypeArgs()
+		if pargs.Len() != aargs.Len() {
+			return false
+		}
+		for i := 0; i < pargs.Len(); i++ {
+			if !bindTypes(bound, pargs.At(i), aargs.At(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return types.Identical(pattern, actual)
+}
+
+// mentionsTypeParam reports whether t refers to the type parameter tp,
+// or to any type parameter if tp is nil.
+func mentionsTypeParam(t types.Type, tp *types.TypeParam) bool {
+	switch x := types.Unalias(t).(type) {
+	case *types.TypeParam:
+		return tp == nil || x == tp
+	case *types.Named:
+		args := x.TypeArgs()
+		for i := 0; i < args.Len(); i++ {
+			if mentionsTypeParam(args.At(i), tp) {
+				return true
+			}
+		}
+	case *types.Pointer:
+		return mentionsTypeParam(x.Elem(), tp)
+	case *types.Slice:
+		return mentionsTypeParam(x.Elem(), tp)
+	case *types.Array:
+		return mentionsTypeParam(x.Elem(), tp)
+	case *types.Chan:
+		return mentionsTypeParam(x.Elem(), tp)
+	case *types.Map:
+		return mentionsTypeParam(x.Key(), tp) || mentionsTypeParam(x.Elem(), tp)
+	case *types.Tuple:
+		for i := 0; i < x.Len(); i++ {
+			if mentionsTypeParam(x.At(i).Type(), tp) {
+				return true
+			}
+		}
+	case *types.Signature:
+		return mentionsTypeParam(x.Params(), tp) || mentionsTypeParam(x.Results(), tp)
+	case *types.Struct:
+		for i := 0; i < x.NumFields(); i++ {
+			if mentionsTypeParam(x.Field(i).Type(), tp) {
+				return true
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < x.NumMethods(); i++ {
+			if mentionsTypeParam(x.Method(i).Type(), tp) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeArgsString returns a list of type arguments as written in Go,
+// like "[K, V]".
+func typeArgsString(targs []types.Type, qf types.Qualifier) string {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, t := range targs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		types.WriteType(&buf, t, qf)
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+// inferredArg reports whether the type of the i-th argument in a call
+// may be used to infer the type arguments of a generic callee. If so,
+// changing the argument's type could change what is instantiated.
+func (c *Checker) inferredArg(ce *ast.CallExpr, i int) bool {
+	fun := ast.Unparen(ce.Fun)
+	explicit := 0
+	switch x := fun.(type) {
+	case *ast.IndexExpr:
+		fun, explicit = x.X, 1
+	case *ast.IndexListExpr:
+		fun, explicit = x.X, len(x.Indices)
+	}
+	var id *ast.Ident
+	switch x := fun.(type) {
+	case *ast.Ident:
+		id = x
+	case *ast.SelectorExpr:
+		id = x.Sel
+	default:
+		return false
+	}
+	fn, ok := c.Uses[id].(*types.Func)
+	if !ok {
+		return false
+	}
+	sign := fn.Origin().Type().(*types.Signature)
+	if sign.TypeParams().Len() <= explicit {
+		return false
+	}
+	_, t := paramVarAndType(sign, i)
+	return t != nil && mentionsTypeParam(t, nil)
+}
+
+// boundMethod returns the name of the method if a conversion is only
+// done to take a method value from it, like x.Close where x's type is a
+// type param.
+func boundMethod(v ssa.Value) string {
+	refs := v.Referrers()
+	if refs == nil || len(*refs) != 1 {
+		return ""
+	}
+	mc, ok := (*refs)[0].(*ssa.MakeClosure)
+	if !ok || len(mc.Bindings) != 1 || mc.Bindings[0] != v {
+		return ""
+	}
+	fn, ok := mc.Fn.(*ssa.Function)
+	if !ok {
+		return ""
+	}
+	if m, ok := fn.Object().(*types.Func); ok {
+		return m.Name()
+	}
+	return ""
+}
+
+// typeParamIssues returns the type params of a generic func whose
+// constraints have more methods than the func needs. They are found in
+// its SSA form, where all method calls on a type param's values and all
+// conversions of them to interfaces are explicit.
+func (c *Checker) typeParamIssues(fd *funcDecl) []Issue {
+	tparams := fd.sign.TypeParams()
+	if tparams.Len() == 0 || fd.ssaFn == nil {
+		return nil
+	}
+	// the methods needed of each type param; nil if unknown
+	used := make(map[*types.TypeParam]map[string]bool, tparams.Len())
+	for i := 0; i < tparams.Len(); i++ {
+		used[tparams.At(i)] = make(map[string]bool)
+	}
+	useIface := func(tp *types.TypeParam, t types.Type) {
+		iface, ok := t.Underlying().(*types.Interface)
+		if !ok {
+			used[tp] = nil
+			return
+		}
+		for i := 0; i < iface.NumMethods(); i++ {
+			used[tp][iface.Method(i).Name()] = true
+		}
+	}
+	tparamOf := func(t types.Type) *types.TypeParam {
+		tp, ok := types.Unalias(t).(*types.TypeParam)
+		if !ok || used[tp] == nil {
+			return nil
+		}
+		return tp
+	}
+	convert := func(tp *types.TypeParam, v ssa.Value) {
+		if name := boundMethod(v); name != "" {
+			used[tp][name] = true
+		} else {
+			useIface(tp, v.Type())
+		}
+	}
+	var visit func(fn *ssa.Function)
+	visit = func(fn *ssa.Function) {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch x := instr.(type) {
+				case *ssa.MakeInterface:
+					if tp := tparamOf(x.X.Type()); tp != nil {
+						convert(tp, x)
+					}
+				case *ssa.ChangeType:
+					if tp := tparamOf(x.X.Type()); tp != nil {
+						convert(tp, x)
+					}
+				case ssa.CallInstruction:
+					common := x.Common()
+					if !common.IsInvoke() {
+						break
+					}
+					if tp := tparamOf(common.Value.Type()); tp != nil {
+						used[tp][common.Method.Name()] = true
+					}
+				}
+			}
+		}
+		for _, anon := range fn.AnonFuncs {
+			visit(anon)
+		}
+	}
+	visit(fd.ssaFn)
+	// the type params passed on to other generic funcs and types must
+	// satisfy their constraints too
+	syntax := fd.ssaFn.Syntax()
+	for id, inst := range c.Instances {
+		if id.Pos() < syntax.Pos() || id.Pos() >= syntax.End() {
+			continue
+		}
+		var otparams *types.TypeParamList
+		switch obj := c.Uses[id].(type) {
+		case *types.Func:
+			if obj.Type() == fd.sign {
+				// a recursive call
+				continue
+			}
+			otparams = obj.Type().(*types.Signature).TypeParams()
+		case *types.TypeName:
+			if named, ok := obj.Type().(*types.Named); ok {
+				otparams = named.TypeParams()
+			}
+		}
+		for i := 0; i < inst.TypeArgs.Len(); i++ {
+			targ := inst.TypeArgs.At(i)
+			for tp := range used {
+				switch {
+				case used[tp] == nil:
+				case types.Identical(targ, tp) && otparams != nil && i < otparams.Len():
+					useIface(tp, otparams.At(i).Constraint())
+				case mentionsTypeParam(targ, tp):
+					// its constraint may depend on tp in ways
+					// we don't follow
+					used[tp] = nil
+				}
+			}
+		}
+	}
+	var issues []Issue
+	for _, field := range fd.ftype.TypeParams.List {
+		for _, name := range field.Names {
+			tp, ok := c.Defs[name].Type().(*types.TypeParam)
+			if !ok || used[tp] == nil {
+				continue
+			}
+			iface, ok := tp.Constraint().Underlying().(*types.Interface)
+			if !ok || !iface.IsMethodSet() {
+				continue
+			}
+			called := make(map[string]string)
+			for i := 0; i < iface.NumMethods(); i++ {
+				m := iface.Method(i)
+				if used[tp][m.Name()] {
+					called[m.Name()] = signString(m.Type().(*types.Signature))
+				}
+			}
+			if len(called) == 0 || len(called) == iface.NumMethods() {
+				continue
+			}
+			tn, targs := c.matchCalled(tp, called)
+			if tn == nil {
+				continue
+			}
+			issue := c.newIssue(name.Name, name.Pos(), tn, targs, called)
+			issue.Func = fd.name
+			issue.TypeParam = tp
+			issue.TypeExpr = field.Type
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
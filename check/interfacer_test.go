@@ -4,12 +4,11 @@
 package check
 
 import (
+	"flag"
 	"fmt"
 	"go/ast"
-	"go/build"
 	"go/parser"
 	"go/token"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -17,32 +16,39 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/kisielk/gotool"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/packages"
 )
 
 const testdata = "testdata"
 
 var (
 	issuesRe = regexp.MustCompile(`^WARN (.*)\n?$`)
-	singleRe = regexp.MustCompile(`([^ ]*) can be ([^ ]*)(,|$)`)
+	singleRe = regexp.MustCompile(`([^ ]*) can be ([^ \[]*(?:\[[^\]]*\])?)(,|$)`)
 )
 
 func goFiles(t *testing.T, p string) []string {
 	if strings.HasSuffix(p, ".go") {
 		return []string{p}
 	}
-	dirs := gotool.ImportPaths([]string{p})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &packages.Config{Mode: packages.NeedFiles}
+	pkgs, err := packages.Load(cfg, p)
+	if err != nil {
+		t.Fatal(err)
+	}
 	var paths []string
-	for _, dir := range dirs {
-		files, err := ioutil.ReadDir(dir)
-		if err != nil {
-			t.Fatal(err)
-		}
-		for _, file := range files {
-			if file.IsDir() {
-				continue
+	for _, pkg := range pkgs {
+		for _, file := range pkg.GoFiles {
+			rel, err := filepath.Rel(wd, file)
+			if err != nil {
+				t.Fatal(err)
 			}
-			paths = append(paths, filepath.Join(dir, file.Name()))
+			paths = append(paths, rel)
 		}
 	}
 	return paths
@@ -61,7 +67,11 @@ func (v *identVisitor) Visit(n ast.Node) ast.Visitor {
 	switch x := n.(type) {
 	case *ast.Ident:
 		line := v.fset.Position(x.Pos()).Line
-		v.idents[identKey(line, x.Name)] = x.Pos()
+		key := identKey(line, x.Name)
+		if _, e := v.idents[key]; !e {
+			// the declaration comes first
+			v.idents[key] = x.Pos()
+		}
 	}
 	return v
 }
@@ -144,12 +154,41 @@ func doTestString(t *testing.T, name, want string, args ...string) {
 	}
 }
 
+// doTestConfig checks args with cfg, like checkArgs, and compares the
+// issues found with want.
+func doTestConfig(t *testing.T, cfg Config, want string, args ...string) {
+	t.Helper()
+	lines, err := checkArgs(cfg, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(lines, "\n"); got != want {
+		t.Errorf("Output mismatch with %+v:\nExpected:\n%s\nGot:\n%s",
+			cfg, want, got)
+	}
+}
+
 func inputPaths(t *testing.T) []string {
 	all, err := filepath.Glob("*")
 	if err != nil {
 		t.Fatal(err)
 	}
-	return all
+	var paths []string
+	for _, p := range all {
+		if strings.HasSuffix(p, ".go") {
+			paths = append(paths, p)
+			continue
+		}
+		// skip go.mod, go.work and the like
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.IsDir() && p != "vendor" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
 }
 
 func chdirUndo(t *testing.T, d string) func() {
@@ -214,7 +253,8 @@ func runNonlocalTests(t *testing.T, paths ...string) {
 	// non-recursive
 	doTest(t, "single")
 	// make sure we don't miss a package's imports
-	doTestString(t, "grab-import", "grab-import/use.go:27:15: s can be grab-import/def/nested.Fooer")
+	doTestString(t, "grab-import", `grab-import/use.go:10:18: rc can be io.Closer
+grab-import/use.go:27:15: s can be grab-import/def/nested.Fooer`)
 	defer chdirUndo(t, "nested/pkg")()
 	// relative paths
 	doTestString(t, "rel-path", "simple.go:12:17: rc can be Closer", "./...")
@@ -224,12 +264,10 @@ func TestMain(m *testing.M) {
 	if err := os.Chdir(testdata); err != nil {
 		panic(err)
 	}
-	wd, err := os.Getwd()
-	if err != nil {
-		panic(err)
-	}
-	build.Default.GOPATH = wd
-	gotool.DefaultContext.BuildContext.GOPATH = wd
+	// The fixtures are modules and workspaces of their own, so
+	// don't let the environment change how they are loaded.
+	os.Unsetenv("GOFLAGS")
+	os.Unsetenv("GOWORK")
 	os.Exit(m.Run())
 }
 
@@ -247,3 +285,383 @@ func TestExtraArg(t *testing.T) {
 		t.Fatalf("Error mismatch:\nExpected:\n%s\nGot:\n%s", want, got)
 	}
 }
+
+func TestAnalyzer(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, filepath.Join(wd, "analysis"), Analyzer, "analysis/use")
+}
+
+func TestAnalyzerDependents(t *testing.T) {
+	if err := Analyzer.Flags.Set("rdeps", "./..."); err != nil {
+		t.Fatal(err)
+	}
+	defer Analyzer.Flags.Set("rdeps", "")
+	_, err := runAnalyzer(new(analysis.Pass))
+	if err == nil {
+		t.Fatal("Expected an error with -rdeps")
+	}
+}
+
+func TestIssueFields(t *testing.T) {
+	defer chdirUndo(t, "files")()
+	pkgs, prog, err := LoadArgs([]string{"import.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := new(Checker)
+	c.Packages(pkgs)
+	c.ProgramSSA(prog)
+	issues, err := c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) == 0 {
+		t.Fatal("wanted at least one issue")
+	}
+	issue := issues[0]
+	got := fmt.Sprintf("%s %s %s %s.%s %v", issue.Func, issue.Param.Name(),
+		issue.Param.Type(), issue.Iface.Pkg().Path(), issue.Iface.Name(),
+		issue.Methods)
+	want := "BasicWrong rc io.ReadCloser io.Closer [Close]"
+	if got != want {
+		t.Fatalf("Issue mismatch:\nExpected:\n%s\nGot:\n%s", want, got)
+	}
+}
+
+func TestFuncLitNames(t *testing.T) {
+	defer chdirUndo(t, "files")()
+	pkgs, prog, err := LoadArgs([]string{"func_lits.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := new(Checker)
+	c.Packages(pkgs)
+	c.ProgramSSA(prog)
+	issues, err := c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, issue := range issues {
+		names = append(names, issue.Func)
+	}
+	// as the gc compiler names their symbols
+	want := "init.func1 Called.func1 Bound.func1 (*st).Nested.func1.1 Generic[...].func1"
+	if got := strings.Join(names, " "); got != want {
+		t.Fatalf("Names mismatch:\nExpected:\n%s\nGot:\n%s", want, got)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	defer chdirUndo(t, "rewrite")()
+	pkgs, prog, err := LoadArgs([]string{"./..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := new(Checker)
+	c.Packages(pkgs)
+	c.ProgramSSA(prog)
+	issues, err := c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := Rewrite(pkgs, issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goldens, err := filepath.Glob("*.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != len(goldens) {
+		t.Fatalf("wanted %d rewritten files, got %d", len(goldens), len(changed))
+	}
+	for _, golden := range goldens {
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, err := filepath.Abs(strings.TrimSuffix(golden, ".golden"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(changed[name]); got != string(want) {
+			t.Errorf("Output mismatch in %s:\nExpected:\n%s\nGot:\n%s",
+				golden, want, got)
+		}
+	}
+}
+
+func TestSynthesize(t *testing.T) {
+	defer chdirUndo(t, "synth")()
+	want := `synth.go:12:15: c can be FlushCloser, a new interface to declare in synth: interface{Close() error; Flush() error}
+synth.go:17:11: c can be FlushCloser, a new interface to declare in synth: interface{Close() error; Flush() error}
+synth.go:22:13: c can be Getter, a new interface to declare in synth: interface{Get(key string) []byte}
+synth.go:26:14: p can be RecvSender, a new interface to declare in synth/peer: interface{Recv() *frame; Send(f *frame) error}`
+	doTestConfig(t, Config{Synthesize: true}, want, "./...")
+}
+
+func TestSuperset(t *testing.T) {
+	defer chdirUndo(t, "superset")()
+	want := `superset.go:9:11: f can be io/fs.File, the closest interface with the methods used
+superset.go:17:12: f can be io.Closer`
+	doTestConfig(t, Config{Superset: true}, want, ".")
+	doTestConfig(t, Config{}, "superset.go:17:12: f can be io.Closer", ".")
+}
+
+func TestCompose(t *testing.T) {
+	defer chdirUndo(t, "compose")()
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{Compose: true, NoCatalog: true}, `compose.go:32:11: c can be interface{ Closer; Reader }
+compose.go:37:12: c can be interface{ Closer; ReadFlusher }
+compose.go:43:12: c can be interface{ Closer; Flusher; Pinger }`},
+		{Config{Compose: true, NoCatalog: true, Synthesize: true}, `compose.go:32:11: c can be ReadCloser, a new interface to declare in compose: interface{Closer; Reader}
+compose.go:37:12: c can be FlushReadCloser, a new interface to declare in compose: interface{Closer; ReadFlusher}
+compose.go:43:12: c can be FlushPingCloser, a new interface to declare in compose: interface{Closer; Flusher; Pinger}
+compose.go:49:12: c can be ResetCloser, a new interface to declare in compose: interface{Close() error; Reset()}`},
+		{Config{Compose: true}, `compose.go:32:11: c can be io.ReadCloser
+compose.go:37:12: c can be interface{ io.Closer; ReadFlusher }
+compose.go:43:12: c can be interface{ io.Closer; Flusher; Pinger }`},
+		{Config{NoCatalog: true}, ``},
+	}
+	for _, tc := range tests {
+		doTestConfig(t, tc.cfg, tc.want, ".")
+	}
+}
+
+func TestInterprocedural(t *testing.T) {
+	defer chdirUndo(t, "interproc")()
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{}, `interproc.go:18:12: f can be io.Reader
+store/store.go:5:11: f can be io.Writer`},
+		// Handler's g can't be narrowed, so neither can f, which
+		// shares its type. The funcs calling each other in a cycle
+		// use the methods called by any of them, unless one of them
+		// keeps the value.
+		{Config{Interprocedural: true}, `interproc.go:9:12: f can be io.ReadCloser
+interproc.go:14:13: f can be io.Reader
+interproc.go:18:12: f can be io.Reader
+interproc.go:23:11: f can be io.Writer
+interproc.go:37:11: f can be io.Reader
+interproc.go:41:11: f can be io.Reader
+interproc.go:59:12: f can be io.ReadCloser
+interproc.go:66:11: f can be io.ReadCloser
+interproc.go:70:12: f can be io.ReadCloser
+store/store.go:5:11: f can be io.Writer`},
+	}
+	for _, tc := range tests {
+		doTestConfig(t, tc.cfg, tc.want, "./...")
+	}
+}
+
+func TestEscapedFuncs(t *testing.T) {
+	defer chdirUndo(t, "escape")()
+	// lib.Handle and Conn.Shut are only used as values by app, and
+	// Conn.Send is only called through an interface declared in app
+	doTestString(t, "escape", `lib/lib.go:9:12: f can be io.Closer
+lib/lib.go:19:21: f can be io.Closer`, "./...")
+	doTestString(t, "escape/lib", `lib/lib.go:5:13: f can be io.Closer
+lib/lib.go:9:12: f can be io.Closer
+lib/lib.go:15:21: f can be io.Closer
+lib/lib.go:19:21: f can be io.Closer
+lib/lib.go:24:21: f can be io.Closer`, "./lib")
+}
+
+func TestMatchSigns(t *testing.T) {
+	defer chdirUndo(t, "files")()
+	// any func with the signature of an interface method or a named
+	// func type in scope is skipped
+	want := "implement.go:26:18: rc can be Closer"
+	doTestConfig(t, Config{MatchSigns: true}, want, "implement.go")
+}
+
+func TestDependents(t *testing.T) {
+	defer chdirUndo(t, "rdeps")()
+	tests := []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{}, `a/a.go:7:22: f can be io.Closer
+a/a.go:11:22: f can be io.Closer
+a/a.go:15:13: f can be io.Closer`},
+		// Conn implements an interface in b, and c uses Handle as a
+		// value
+		{Config{Dependents: []string{"./..."}}, "a/a.go:11:22: f can be io.Closer"},
+	}
+	for _, tc := range tests {
+		doTestConfig(t, tc.cfg, tc.want, "./a")
+	}
+}
+
+func TestDepth(t *testing.T) {
+	defer chdirUndo(t, "depth")()
+	const (
+		shut    = "depth.go:13:11: t can be depth/c.Closer"
+		shutStd = "depth.go:13:11: t can be io.Closer"
+		sync    = "depth.go:17:11: t can be depth/b.Flusher"
+		size    = "depth.go:21:14: t can be depth/a.Sizer"
+	)
+	tests := []struct {
+		flags []string
+		want  []string
+	}{
+		{nil, []string{shutStd, sync, size}},
+		{[]string{"-nocatalog"}, []string{sync, size}},
+		{[]string{"-depth=0"}, []string{shutStd}},
+		{[]string{"-depth=0", "-nocatalog"}, nil},
+		{[]string{"-depth=1"}, []string{shutStd, size}},
+		{[]string{"-depth=1", "-nocatalog"}, []string{size}},
+		{[]string{"-depth=3"}, []string{shutStd, sync, size}},
+		{[]string{"-depth=3", "-nocatalog"}, []string{shut, sync, size}},
+		{[]string{"-depth=-1", "-nocatalog"}, []string{shut, sync, size}},
+		{[]string{"-pkgs=depth/c"}, []string{shut}},
+		{[]string{"-pkgs=depth/..."}, []string{shut, sync, size}},
+	}
+	for _, tc := range tests {
+		var cfg Config
+		fs := flag.NewFlagSet("", flag.ContinueOnError)
+		cfg.RegisterFlags(fs)
+		if err := fs.Parse(tc.flags); err != nil {
+			t.Fatal(err)
+		}
+		doTestConfig(t, cfg, strings.Join(tc.want, "\n"), ".")
+	}
+}
+
+func TestRanking(t *testing.T) {
+	defer chdirUndo(t, "ranking")()
+	tests := []struct {
+		cfg  Config
+		want []string
+	}{
+		{Config{}, []string{
+			"ranking.go:32:11: c can be io.Closer",
+			"ranking.go:36:11: c can be ranking/dep.Flusher",
+			"ranking.go:40:11: c can be fmt.Stringer",
+		}},
+		{Config{Alternatives: true}, []string{
+			"ranking.go:32:11: c can be io.Closer (alternatives: ranking/dep.Closer, Closer)",
+			"ranking.go:36:11: c can be ranking/dep.Flusher (alternatives: Flusher, ranking/dep.Drainer)",
+			"ranking.go:40:11: c can be fmt.Stringer (alternatives: Stringer)",
+		}},
+		{Config{Alternatives: true, NoCatalog: true, Pkgs: []string{"ranking/..."}}, []string{
+			"ranking.go:32:11: c can be ranking/dep.Closer (alternatives: Closer)",
+			"ranking.go:36:11: c can be ranking/dep.Flusher (alternatives: Flusher, ranking/dep.Drainer)",
+			"ranking.go:40:11: c can be Stringer",
+		}},
+	}
+	for _, tc := range tests {
+		doTestConfig(t, tc.cfg, strings.Join(tc.want, "\n"), ".")
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	defer chdirUndo(t, "catalog")()
+	doTest(t, ".")
+	doTestConfig(t, Config{NoCatalog: true}, "", ".")
+	// its interfaces use the types of the package's imports
+	func() {
+		defer chdirUndo(t, "deps")()
+		doTest(t, ".")
+	}()
+	defer chdirUndo(t, "old")()
+	doTest(t, ".")
+}
+
+func doTestDiagnostics(t *testing.T, name, want string) {
+	t.Helper()
+	defer chdirUndo(t, "files")()
+	pkgs, prog, err := LoadArgs([]string{name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := new(Checker)
+	c.Packages(pkgs)
+	c.ProgramSSA(prog)
+	if _, err := c.Check(); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, d := range c.Diagnostics() {
+		pos := prog.Fset.Position(d.Pos)
+		pos.Filename = filepath.Base(pos.Filename)
+		lines = append(lines, fmt.Sprintf("%s: %s", pos, d.Message))
+	}
+	if got := strings.Join(lines, "\n"); got != want {
+		t.Fatalf("Output mismatch in %s:\nExpected:\n%s\nGot:\n%s", name, want, got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	// the methods with pointer receivers can be called on the params,
+	// but they aren't in the method sets of their types
+	doTestDiagnostics(t, "verify.go", `verify.go:9:14: c can't be io.Closer: Conn does not satisfy it (method Close has a pointer receiver)
+verify.go:21:15: p can't be io.Closer: Pair does not satisfy it (method Close has a pointer receiver)`)
+}
+
+func TestTypedNil(t *testing.T) {
+	doTestDiagnostics(t, "typed_nil.go", `typed_nil.go:9:5: f can't be io.Closer: comparing it with nil would miss a nil *os.File
+typed_nil.go:15:5: f can't be io.Closer: comparing it with nil would miss a nil *os.File
+typed_nil.go:22:5: f can't be io.Closer: comparing it with nil would miss a nil *os.File
+typed_nil.go:30:7: f can't be io.Closer: comparing it with nil would miss a nil *os.File
+typed_nil.go:49:5: f can't be io.Closer: comparing it with nil would miss a nil *os.File
+typed_nil.go:56:5: f can't be io.Closer: comparing it with nil would miss a nil *os.File
+typed_nil.go:64:9: f can't be io.Closer: comparing it with nil would miss a nil *os.File`)
+}
+
+func TestSinks(t *testing.T) {
+	defer chdirUndo(t, "sinks")()
+	tests := []struct {
+		sinks []string
+		want  string
+	}{
+		{nil, `sinks.go:11:16: f can be io.Closer, though it's observed through reflection by encoding/json.Marshal
+sinks.go:16:14: f can be io.Closer, though it's observed through reflection by (*encoding/json.Encoder).Encode
+sinks.go:21:16: f can be io.Closer, though it's observed through reflection by reflect.ValueOf
+sinks.go:26:15: f can be io.Closer, though it's observed through reflection by reflect.TypeOf
+sinks.go:32:14: f can be io.Closer, though it's observed through reflection by fmt.Printf
+sinks.go:37:18: f can be io.Closer
+sinks.go:42:21: f can be io.Closer, though it's observed through reflection by fmt.Printf
+sinks.go:47:21: f can be io.Closer
+sinks.go:52:18: f can be io.Closer, though it's observed through reflection by fmt.Println
+sinks.go:57:13: f can be io.Closer, though it's observed through reflection by (*log.Logger).Print
+sinks.go:62:16: f can be io.WriteCloser
+sinks.go:67:20: f can be io.Closer, though it's observed through reflection by fmt.Printf`},
+		{[]string{"reflect.ValueOf"}, `sinks.go:11:16: f can be io.Closer
+sinks.go:16:14: f can be io.Closer
+sinks.go:21:16: f can be io.Closer, though it's observed through reflection by reflect.ValueOf
+sinks.go:26:15: f can be io.Closer
+sinks.go:32:14: f can be io.Closer
+sinks.go:37:18: f can be io.Closer
+sinks.go:42:21: f can be io.Closer
+sinks.go:47:21: f can be io.Closer
+sinks.go:52:18: f can be io.Closer
+sinks.go:57:13: f can be io.Closer
+sinks.go:62:16: f can be io.WriteCloser
+sinks.go:67:20: f can be io.Closer`},
+		{[]string{"none"}, `sinks.go:11:16: f can be io.Closer
+sinks.go:16:14: f can be io.Closer
+sinks.go:21:16: f can be io.Closer
+sinks.go:26:15: f can be io.Closer
+sinks.go:32:14: f can be io.Closer
+sinks.go:37:18: f can be io.Closer
+sinks.go:42:21: f can be io.Closer
+sinks.go:47:21: f can be io.Closer
+sinks.go:52:18: f can be io.Closer
+sinks.go:57:13: f can be io.Closer
+sinks.go:62:16: f can be io.WriteCloser
+sinks.go:67:20: f can be io.Closer`},
+	}
+	for _, tc := range tests {
+		doTestConfig(t, Config{Sinks: tc.sinks}, tc.want, ".")
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"go/ast"
+	"go/types"
+	"maps"
+	"slices"
+)
+
+// passTo records that e is passed as is to the param callee, so that
+// it counts as used as whatever interface callee is narrowed to. It
+// reports whether e is a variable that may be narrowed.
+func (c *Checker) passTo(e ast.Expr, callee *types.Var) bool {
+	if types.IsInterface(callee.Type()) {
+		return false
+	}
+	usage := c.varUsage(e)
+	if usage == nil {
+		return false
+	}
+	usage.passed[callee] = struct{}{}
+	return true
+}
+
+// pending reports whether a variable is passed to any param that isn't
+// known to be narrowed yet.
+func pending(usage *varUsage) bool {
+	if len(usage.passed) > 0 {
+		return true
+	}
+	for to := range usage.assigned {
+		if pending(to) {
+			return true
+		}
+	}
+	return false
+}
+
+// narrowCalls finds the params that can be narrowed when passing a
+// variable to another func's param counts as using the interface the
+// latter is narrowed to. Starting from the params that aren't passed
+// to any other, it iterates until no more are narrowed, resolving the
+// cycles of calls along the way. The variables passed to params that
+// can't be narrowed are discarded.
+func (c *Checker) narrowCalls() {
+	failed := make(map[*types.Var]bool)
+	for {
+		for _, usage := range c.vars {
+			for callee := range usage.passed {
+				t := c.narrowed[callee]
+				if t == nil {
+					continue
+				}
+				iface := t.Underlying().(*types.Interface)
+				for i := 0; i < iface.NumMethods(); i++ {
+					usage.calls[iface.Method(i).Name()] = struct{}{}
+				}
+				delete(usage.passed, callee)
+			}
+		}
+		changed := false
+		for _, fd := range c.funcs {
+			if c.funcEscapes(fd) {
+				continue
+			}
+			for _, group := range fd.paramGroups() {
+				if c.narrowGroup(fd, group) {
+					changed = true
+				}
+			}
+		}
+		if !changed && !c.narrowCycle(failed) {
+			break
+		}
+	}
+	for _, usage := range c.vars {
+		if len(usage.passed) > 0 {
+			usage.discard = true
+		}
+	}
+}
+
+// narrowGroup records the interface types the params in a group can be
+// declared as, if they aren't yet. Like when reporting them, either all
+// of them are narrowed or none are. It reports whether they were.
+func (c *Checker) narrowGroup(fd *funcDecl, group []*types.Var) bool {
+	for _, param := range group {
+		if c.narrowed[param] != nil {
+			return false
+		}
+		if usage := c.vars[param]; usage != nil && pending(usage) {
+			return false
+		}
+	}
+	pts := c.groupNewTypes(fd, group)
+	if pts == nil {
+		return false
+	}
+	narrowed := make([]types.Type, len(pts))
+	for i, pt := range pts {
+		t := pt.iface.Type()
+		if len(pt.targs) > 0 {
+			inst, err := types.Instantiate(nil, t, pt.targs, false)
+			if err != nil {
+				return false
+			}
+			t = inst
+		}
+		narrowed[i] = t
+	}
+	for i, pt := range pts {
+		c.narrowed[pt.param] = narrowed[i]
+	}
+	return true
+}
+
+// passedTo adds to params those a variable is passed to, directly or
+// via the variables it's assigned to.
+func passedTo(usage *varUsage, params map[*types.Var]bool, seen map[*varUsage]bool) {
+	if seen[usage] {
+		return
+	}
+	seen[usage] = true
+	for callee := range usage.passed {
+		params[callee] = true
+	}
+	for to := range usage.assigned {
+		passedTo(to, params, seen)
+	}
+}
+
+// narrowCycle narrows the params in a cycle of calls, like funcs passing
+// a variable to each other, which would otherwise wait on each other
+// forever. It looks for the cycles only passing to each other, the
+// rest of the params they are passed to being narrowed already. As the
+// params in a cycle end up holding each other's values, each is seeded
+// with the union of the methods called on all of them, and either all
+// of them are narrowed to that or none are, in which case they are
+// added to failed. It reports whether a cycle was narrowed.
+func (c *Checker) narrowCycle(failed map[*types.Var]bool) bool {
+	var params []*types.Var
+	funcOf := make(map[*types.Var]*funcDecl)
+	for _, fd := range c.funcs {
+		if c.funcEscapes(fd) {
+			continue
+		}
+		sparams := fd.sign.Params()
+		for i := 0; i < sparams.Len(); i++ {
+			param := sparams.At(i)
+			usage := c.vars[param]
+			if usage == nil || failed[param] || c.narrowed[param] != nil || !pending(usage) {
+				continue
+			}
+			params = append(params, param)
+			funcOf[param] = fd
+		}
+	}
+	edges := make(map[*types.Var]map[*types.Var]bool, len(params))
+	for _, param := range params {
+		edges[param] = make(map[*types.Var]bool)
+		passedTo(c.vars[param], edges[param], make(map[*varUsage]bool))
+	}
+	for _, scc := range sccs(params, edges) {
+		in := make(map[*types.Var]bool, len(scc))
+		for _, param := range scc {
+			in[param] = true
+		}
+		closed := true
+		for _, param := range scc {
+			for to := range edges[param] {
+				if !in[to] {
+					closed = false
+				}
+			}
+		}
+		if !closed {
+			continue
+		}
+		if c.narrowSCC(scc, in, funcOf) {
+			return true
+		}
+		for _, param := range scc {
+			failed[param] = true
+		}
+	}
+	return false
+}
+
+// narrowSCC tries to narrow the params in a cycle, as per narrowCycle.
+// If it can't, their usage is left as it was.
+func (c *Checker) narrowSCC(scc []*types.Var, in map[*types.Var]bool, funcOf map[*types.Var]*funcDecl) bool {
+	var usages []*varUsage
+	seen := make(map[*varUsage]bool)
+	var collect func(usage *varUsage)
+	collect = func(usage *varUsage) {
+		if seen[usage] {
+			return
+		}
+		seen[usage] = true
+		usages = append(usages, usage)
+		for to := range usage.assigned {
+			collect(to)
+		}
+	}
+	for _, param := range scc {
+		collect(c.vars[param])
+	}
+	union := make(map[string]struct{})
+	for _, usage := range usages {
+		for name := range usage.calls {
+			union[name] = struct{}{}
+		}
+	}
+	type saved struct {
+		calls  map[string]struct{}
+		passed map[*types.Var]struct{}
+	}
+	prev := make(map[*varUsage]saved, len(usages))
+	for _, usage := range usages {
+		prev[usage] = saved{maps.Clone(usage.calls), maps.Clone(usage.passed)}
+		for callee := range usage.passed {
+			if in[callee] {
+				delete(usage.passed, callee)
+			}
+		}
+	}
+	for _, param := range scc {
+		maps.Copy(c.vars[param].calls, union)
+	}
+	var narrowed []*types.Var
+	for _, param := range scc {
+		if c.narrowed[param] != nil {
+			// along with another in its group
+			continue
+		}
+		fd := funcOf[param]
+		group := groupOf(fd, param)
+		if !c.narrowGroup(fd, group) {
+			for _, usage := range usages {
+				usage.calls = prev[usage].calls
+				usage.passed = prev[usage].passed
+			}
+			for _, param := range narrowed {
+				delete(c.narrowed, param)
+			}
+			return false
+		}
+		narrowed = append(narrowed, group...)
+	}
+	return true
+}
+
+// groupOf returns the group of params in fd that param belongs to.
+func groupOf(fd *funcDecl, param *types.Var) []*types.Var {
+	for _, group := range fd.paramGroups() {
+		if slices.Contains(group, param) {
+			return group
+		}
+	}
+	return nil
+}
+
+// sccs returns the strongly connected components of the graph with the
+// given nodes and edges, such that each comes after those it has edges
+// to. The edges to other nodes are ignored.
+func sccs(nodes []*types.Var, edges map[*types.Var]map[*types.Var]bool) [][]*types.Var {
+	index := make(map[*types.Var]int, len(nodes))
+	low := make(map[*types.Var]int, len(nodes))
+	onStack := make(map[*types.Var]bool)
+	var stack []*types.Var
+	var result [][]*types.Var
+	var visit func(v *types.Var)
+	visit = func(v *types.Var) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		// the edges in the order of the nodes, for determinism
+		for _, w := range nodes {
+			if !edges[v][w] {
+				continue
+			}
+			if _, e := index[w]; !e {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var scc []*types.Var
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			scc = append(scc, w)
+			if w == v {
+				break
+			}
+		}
+		result = append(result, scc)
+	}
+	for _, v := range nodes {
+		if _, e := index[v]; !e {
+			visit(v)
+		}
+	}
+	return result
+}
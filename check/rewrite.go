@@ -0,0 +1,620 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Rewrite applies the suggestions in issues to the source files they
+// were found in, which must belong to pkgs. It returns the new contents
+// of each modified file, keyed by filename; nothing is written to disk.
+//
+// Only the type expressions of the reported params and the file's
+// imports are changed. The rest of each file is left untouched. Issues
+// proposing new interfaces, whose declarations would have to be added
+// first, are skipped.
+func Rewrite(pkgs []*packages.Package, issues []Issue) (map[string][]byte, error) {
+	type fileIssues struct {
+		pkg    *packages.Package
+		file   *ast.File
+		issues []Issue
+	}
+	var files []*fileIssues
+	byFile := make(map[*ast.File]*fileIssues)
+	for _, issue := range issues {
+		if issue.Decl != "" {
+			continue
+		}
+		pkg, file := issueFile(pkgs, issue)
+		if file == nil {
+			return nil, fmt.Errorf("could not find the file declaring %s", issue.obj().Name())
+		}
+		fi := byFile[file]
+		if fi == nil {
+			fi = &fileIssues{pkg: pkg, file: file}
+			byFile[file] = fi
+			files = append(files, fi)
+		}
+		fi.issues = append(fi.issues, issue)
+	}
+	changed := make(map[string][]byte, len(files))
+	for _, fi := range files {
+		name := fi.pkg.Fset.File(fi.file.Pos()).Name()
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		fr := &fileRewriter{
+			fset: fi.pkg.Fset,
+			pkg:  fi.pkg.Types,
+			info: fi.pkg.TypesInfo,
+			file: fi.file,
+			src:  src,
+		}
+		out, err := fr.rewrite(fi.issues)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), name, out, parser.ParseComments); err != nil {
+			return nil, fmt.Errorf("rewriting produced invalid code: %v", err)
+		}
+		changed[name] = out
+	}
+	return changed, nil
+}
+
+func issueFile(pkgs []*packages.Package, issue Issue) (*packages.Package, *ast.File) {
+	pos := issue.Pos()
+	for _, pkg := range pkgs {
+		if pkg.Types != issue.obj().Pkg() {
+			continue
+		}
+		for _, file := range pkg.Syntax {
+			if file.Pos() <= pos && pos < file.End() {
+				return pkg, file
+			}
+		}
+	}
+	return nil, nil
+}
+
+// textEdit replaces the source bytes between two offsets.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+type fileRewriter struct {
+	fset *token.FileSet
+	pkg  *types.Package
+	info *types.Info
+	file *ast.File
+	src  []byte
+
+	edits []textEdit
+
+	// newImports holds the imports to be added, by path.
+	newImports map[string]*newImport
+	// reused holds the existing imports used by the new types.
+	reused map[*ast.ImportSpec]bool
+}
+
+type newImport struct {
+	name  string // name used in the file
+	alias bool   // whether name differs from the package name
+}
+
+func (fr *fileRewriter) offset(pos token.Pos) int {
+	return fr.fset.Position(pos).Offset
+}
+
+func (fr *fileRewriter) replace(start, end token.Pos, text string) {
+	fr.edits = append(fr.edits, textEdit{fr.offset(start), fr.offset(end), text})
+}
+
+func (fr *fileRewriter) rewrite(issues []Issue) ([]byte, error) {
+	fr.newImports = make(map[string]*newImport)
+	fr.reused = make(map[*ast.ImportSpec]bool)
+	// group the issues by param field, as in "a, b *T"
+	var exprs []ast.Expr
+	byExpr := make(map[ast.Expr][]Issue)
+	for _, issue := range issues {
+		if byExpr[issue.TypeExpr] == nil {
+			exprs = append(exprs, issue.TypeExpr)
+		}
+		byExpr[issue.TypeExpr] = append(byExpr[issue.TypeExpr], issue)
+	}
+	for _, expr := range exprs {
+		if err := fr.rewriteField(fr.fieldOf(expr), byExpr[expr]); err != nil {
+			return nil, err
+		}
+	}
+	fr.fixImports()
+	return applyEdits(fr.src, fr.edits), nil
+}
+
+func (fr *fileRewriter) fieldOf(expr ast.Expr) *ast.Field {
+	var field *ast.Field
+	ast.Inspect(fr.file, func(node ast.Node) bool {
+		if f, ok := node.(*ast.Field); ok && f.Type == expr {
+			field = f
+		}
+		return field == nil
+	})
+	return field
+}
+
+func (fr *fileRewriter) rewriteField(field *ast.Field, issues []Issue) error {
+	if field == nil {
+		return fmt.Errorf("issues don't match their param group")
+	}
+	// the names without an issue keep their type
+	names := make([]string, len(field.Names))
+	matched := 0
+	for i, id := range field.Names {
+		for _, issue := range issues {
+			if issue.Pos() != id.Pos() {
+				continue
+			}
+			name, err := fr.typeString(issue)
+			if err != nil {
+				return err
+			}
+			names[i] = name
+			matched++
+		}
+		if names[i] == "" {
+			names[i] = fr.keepType(field.Type)
+		}
+	}
+	if matched != len(issues) {
+		return fmt.Errorf("issues don't match their param group")
+	}
+	same := true
+	for _, name := range names[1:] {
+		if name != names[0] {
+			same = false
+		}
+	}
+	if same {
+		fr.replace(field.Type.Pos(), field.Type.End(), names[0])
+		return nil
+	}
+	// the params need different types, so split the group; struct
+	// fields go on their own lines, each with the tag
+	sep, tag, end := ", ", "", field.Type.End()
+	if issues[0].Struct != "" {
+		sep = "; "
+		if indent := fr.src[fr.lineStart(field.Pos()):fr.offset(field.Pos())]; isBlank(indent) {
+			sep = "\n" + string(indent)
+		}
+		if field.Tag != nil {
+			tag = " " + field.Tag.Value
+			end = field.Tag.End()
+		}
+	}
+	var buf bytes.Buffer
+	for i, id := range field.Names {
+		if i > 0 {
+			buf.WriteString(sep)
+		}
+		fmt.Fprintf(&buf, "%s %s%s", id.Name, names[i], tag)
+	}
+	fr.replace(field.Names[0].Pos(), end, buf.String())
+	return nil
+}
+
+// keepType returns the source of a type expression which is copied as
+// is, recording the imports it needs to be kept.
+func (fr *fileRewriter) keepType(expr ast.Expr) string {
+	ast.Inspect(expr, func(node ast.Node) bool {
+		id, ok := node.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		pn, ok := fr.info.Uses[id].(*types.PkgName)
+		if !ok {
+			return true
+		}
+		for _, spec := range fr.file.Imports {
+			if fr.info.PkgNameOf(spec) == pn {
+				fr.reused[spec] = true
+			}
+		}
+		return true
+	})
+	return string(fr.src[fr.offset(expr.Pos()):fr.offset(expr.End())])
+}
+
+// typeString returns how the type suggested by an issue must be written
+// in the file, recording any imports needed for it.
+func (fr *fileRewriter) typeString(issue Issue) (string, error) {
+	if issue.Iface == nil {
+		names := make([]string, len(issue.Embeds))
+		for i, embed := range issue.Embeds {
+			name, err := fr.qualify(embed)
+			if err != nil {
+				return "", err
+			}
+			names[i] = name
+		}
+		return "interface{ " + strings.Join(names, "; ") + " }", nil
+	}
+	name, err := fr.qualify(issue.Iface)
+	if err != nil || len(issue.IfaceArgs) == 0 {
+		return name, err
+	}
+	name += typeArgsString(issue.IfaceArgs, func(pkg *types.Package) string {
+		if pkg == fr.pkg {
+			return ""
+		}
+		pname, perr := fr.pkgName(pkg)
+		if perr != nil && err == nil {
+			err = perr
+		}
+		return pname
+	})
+	return name, err
+}
+
+// qualify returns how tn must be written in the file, recording any
+// import that needs to be added for it.
+func (fr *fileRewriter) qualify(tn *types.TypeName) (string, error) {
+	pkg := tn.Pkg()
+	if pkg == nil || pkg == fr.pkg {
+		return tn.Name(), nil
+	}
+	name, err := fr.pkgName(pkg)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return tn.Name(), nil
+	}
+	return name + "." + tn.Name(), nil
+}
+
+// pkgName returns the name pkg must be referred to with in the file,
+// which is empty if it's dot-imported, recording the import that needs
+// to be added or kept for it.
+func (fr *fileRewriter) pkgName(pkg *types.Package) (string, error) {
+	path := pkg.Path()
+	if !canImport(fr.pkg.Path(), path) {
+		return "", fmt.Errorf("cannot import %s from %s", path, fr.pkg.Path())
+	}
+	for _, spec := range fr.file.Imports {
+		if importPath(spec) != path {
+			continue
+		}
+		if spec.Name != nil {
+			switch spec.Name.Name {
+			case ".":
+				return "", nil
+			case "_":
+				continue
+			}
+		}
+		if pn := fr.info.PkgNameOf(spec); pn != nil {
+			fr.reused[spec] = true
+			return pn.Name(), nil
+		}
+	}
+	if imp := fr.newImports[path]; imp != nil {
+		return imp.name, nil
+	}
+	name := pkg.Name()
+	for i := 2; fr.nameTaken(name); i++ {
+		name = pkg.Name() + strconv.Itoa(i)
+	}
+	fr.newImports[path] = &newImport{name: name, alias: name != pkg.Name()}
+	return name, nil
+}
+
+func (fr *fileRewriter) nameTaken(name string) bool {
+	for _, spec := range fr.file.Imports {
+		if pn := fr.info.PkgNameOf(spec); pn != nil && pn.Name() == name {
+			return true
+		}
+	}
+	for _, imp := range fr.newImports {
+		if imp.name == name {
+			return true
+		}
+	}
+	return fr.pkg.Scope().Lookup(name) != nil
+}
+
+// canImport reports whether a package may import another, as per the
+// rules for internal directories.
+func canImport(from, path string) bool {
+	i := strings.LastIndex(path, "/internal/")
+	switch {
+	case i >= 0:
+	case strings.HasSuffix(path, "/internal"):
+		i = len(path) - len("/internal")
+	case path == "internal" || strings.HasPrefix(path, "internal/"):
+		// std internal packages
+		return false
+	default:
+		return true
+	}
+	parent := path[:i]
+	return from == parent || strings.HasPrefix(from, parent+"/")
+}
+
+func importPath(spec *ast.ImportSpec) string {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+func (fr *fileRewriter) inEdit(pos token.Pos) bool {
+	off := fr.offset(pos)
+	for _, e := range fr.edits {
+		if e.start <= off && off < e.end {
+			return true
+		}
+	}
+	return false
+}
+
+// unusedImports returns the imports which were used before the edits,
+// but are no longer.
+func (fr *fileRewriter) unusedImports() map[*ast.ImportSpec]bool {
+	uses := make(map[*types.PkgName]int)
+	kept := make(map[*types.PkgName]int)
+	for id, obj := range fr.info.Uses {
+		pn, ok := obj.(*types.PkgName)
+		if !ok || id.Pos() < fr.file.Pos() || id.Pos() >= fr.file.End() {
+			continue
+		}
+		uses[pn]++
+		if !fr.inEdit(id.Pos()) {
+			kept[pn]++
+		}
+	}
+	unused := make(map[*ast.ImportSpec]bool)
+	for _, spec := range fr.file.Imports {
+		if spec.Name != nil && (spec.Name.Name == "_" || spec.Name.Name == ".") {
+			continue
+		}
+		pn := fr.info.PkgNameOf(spec)
+		if pn != nil && uses[pn] > 0 && kept[pn] == 0 && !fr.reused[spec] {
+			unused[spec] = true
+		}
+	}
+	return unused
+}
+
+func (fr *fileRewriter) specText(path string) string {
+	if imp := fr.newImports[path]; imp.alias {
+		return imp.name + " " + strconv.Quote(path)
+	}
+	return strconv.Quote(path)
+}
+
+func isStd(path string) bool {
+	return !strings.Contains(strings.Split(path, "/")[0], ".")
+}
+
+// fixImports adds the edits to remove the imports that are no longer
+// used and to add the new ones.
+func (fr *fileRewriter) fixImports() {
+	unused := fr.unusedImports()
+	var paths []string
+	for path := range fr.newImports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var decls []*ast.GenDecl
+	for _, decl := range fr.file.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			decls = append(decls, gd)
+		}
+	}
+	if len(decls) == 0 {
+		if len(paths) > 0 {
+			fr.edits = append(fr.edits, textEdit{
+				start: fr.lineEnd(fr.file.Name.End()),
+				end:   fr.lineEnd(fr.file.Name.End()),
+				text:  "\n\n" + fr.importDecl(nil, paths),
+			})
+		}
+		return
+	}
+	for i, gd := range decls {
+		var add []string
+		if i == 0 {
+			add = paths
+		}
+		if !gd.Lparen.IsValid() {
+			spec := gd.Specs[0].(*ast.ImportSpec)
+			switch {
+			case unused[spec] && len(add) == 0:
+				fr.removeLines(gd.Pos(), gd.End(), true)
+			case unused[spec] || len(add) > 0:
+				var kept []ast.Spec
+				if !unused[spec] {
+					kept = gd.Specs
+				}
+				fr.replace(gd.Pos(), gd.End(), fr.importDecl(kept, add))
+			}
+			continue
+		}
+		removed := 0
+		for _, spec := range gd.Specs {
+			if unused[spec.(*ast.ImportSpec)] {
+				removed++
+			}
+		}
+		if removed == len(gd.Specs) && len(add) == 0 {
+			fr.removeLines(gd.Pos(), gd.End(), true)
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if unused[spec.(*ast.ImportSpec)] {
+				fr.removeLines(spec.Pos(), spec.End(), false)
+			}
+		}
+		for _, path := range add {
+			fr.insertSpec(gd, path)
+		}
+	}
+}
+
+// importDecl returns the source of an import declaration with the given
+// existing specs plus the new import paths.
+func (fr *fileRewriter) importDecl(kept []ast.Spec, add []string) string {
+	type line struct{ path, text string }
+	var lines []line
+	for _, spec := range kept {
+		is := spec.(*ast.ImportSpec)
+		text := string(fr.src[fr.offset(is.Pos()):fr.offset(is.End())])
+		lines = append(lines, line{importPath(is), text})
+	}
+	for _, path := range add {
+		lines = append(lines, line{path, fr.specText(path)})
+	}
+	if len(lines) == 1 {
+		return "import " + lines[0].text
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].path < lines[j].path
+	})
+	var buf bytes.Buffer
+	buf.WriteString("import (\n")
+	for _, l := range lines {
+		fmt.Fprintf(&buf, "\t%s\n", l.text)
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
+// insertSpec adds a new import line to a parenthesized import
+// declaration, keeping it sorted within the first group of std or
+// non-std imports, depending on the path.
+func (fr *fileRewriter) insertSpec(gd *ast.GenDecl, path string) {
+	text := fr.specText(path)
+	// split the specs into the groups separated by empty lines
+	var groups [][]*ast.ImportSpec
+	lastLine := 0
+	for _, spec := range gd.Specs {
+		is := spec.(*ast.ImportSpec)
+		line := fr.fset.Position(is.Pos()).Line
+		if len(groups) == 0 || line > lastLine+1 {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], is)
+		lastLine = fr.fset.Position(is.End()).Line
+	}
+	if len(groups) == 0 {
+		fr.edits = append(fr.edits, textEdit{
+			start: fr.offset(gd.Rparen),
+			end:   fr.offset(gd.Rparen),
+			text:  "\t" + text + "\n",
+		})
+		return
+	}
+	group := groups[0]
+	if !isStd(path) {
+		group = groups[len(groups)-1]
+	}
+	for _, g := range groups {
+		if isStd(importPath(g[0])) == isStd(path) {
+			group = g
+			break
+		}
+	}
+	for _, is := range group {
+		if importPath(is) > path {
+			start := fr.lineStart(is.Pos())
+			indent := string(fr.src[start:fr.offset(is.Pos())])
+			fr.edits = append(fr.edits, textEdit{start, start, indent + text + "\n"})
+			return
+		}
+	}
+	last := group[len(group)-1]
+	start := fr.lineStart(last.Pos())
+	indent := string(fr.src[start:fr.offset(last.Pos())])
+	end := fr.lineEnd(last.End())
+	fr.edits = append(fr.edits, textEdit{end, end, "\n" + indent + text})
+}
+
+func (fr *fileRewriter) lineStart(pos token.Pos) int {
+	off := fr.offset(pos)
+	return bytes.LastIndexByte(fr.src[:off], '\n') + 1
+}
+
+// lineEnd returns the offset of the newline ending the line at pos, or
+// the end of the source if there is none.
+func (fr *fileRewriter) lineEnd(pos token.Pos) int {
+	off := fr.offset(pos)
+	if i := bytes.IndexByte(fr.src[off:], '\n'); i >= 0 {
+		return off + i
+	}
+	return len(fr.src)
+}
+
+func isBlank(b []byte) bool {
+	return len(bytes.TrimSpace(b)) == 0
+}
+
+// removeLines removes the source between start and end, along with
+// their lines if nothing else is on them. If collapse is true, a blank
+// line left on each side is merged into one.
+func (fr *fileRewriter) removeLines(start, end token.Pos, collapse bool) {
+	from, to := fr.offset(start), fr.offset(end)
+	lstart, lend := fr.lineStart(start), fr.lineEnd(end)
+	// a trailing line comment goes away with the line
+	rest := bytes.TrimSpace(fr.src[to:lend])
+	if !isBlank(fr.src[lstart:from]) || (len(rest) > 0 && !bytes.HasPrefix(rest, []byte("//"))) {
+		fr.edits = append(fr.edits, textEdit{from, to, ""})
+		return
+	}
+	if lend < len(fr.src) {
+		lend++ // the newline
+	}
+	if collapse && lstart > 0 && lend < len(fr.src) {
+		prev := bytes.LastIndexByte(fr.src[:lstart-1], '\n') + 1
+		next := bytes.IndexByte(fr.src[lend:], '\n')
+		if next >= 0 && isBlank(fr.src[prev:lstart]) && isBlank(fr.src[lend:lend+next]) {
+			lend += next + 1
+		}
+	}
+	fr.edits = append(fr.edits, textEdit{lstart, lend, ""})
+}
+
+func applyEdits(src []byte, edits []textEdit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		// insertions go before replacements at the same offset
+		return edits[i].end < edits[j].end
+	})
+	var buf bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		buf.Write(src[last:e.start])
+		buf.WriteString(e.text)
+		last = e.end
+	}
+	buf.Write(src[last:])
+	return buf.Bytes()
+}
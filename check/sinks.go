@@ -0,0 +1,172 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// DefaultSinks are the funcs and methods which observe the dynamic type
+// of the values passed to them through reflection, used if
+// Config.Sinks is empty. They are named like types.Func.FullName.
+var DefaultSinks = []string{
+	"encoding/json.Marshal",
+	"encoding/json.MarshalIndent",
+	"(*encoding/json.Encoder).Encode",
+	"encoding/xml.Marshal",
+	"encoding/xml.MarshalIndent",
+	"(*encoding/xml.Encoder).Encode",
+	"(*encoding/gob.Encoder).Encode",
+	"(*encoding/gob.Encoder).EncodeValue",
+	"reflect.ValueOf",
+	"reflect.TypeOf",
+	"fmt.Print",
+	"fmt.Println",
+	"fmt.Printf",
+	"fmt.Sprint",
+	"fmt.Sprintln",
+	"fmt.Sprintf",
+	"fmt.Fprint",
+	"fmt.Fprintln",
+	"fmt.Fprintf",
+	"fmt.Errorf",
+	"fmt.Append",
+	"fmt.Appendln",
+	"fmt.Appendf",
+	"log.Print",
+	"log.Println",
+	"log.Printf",
+	"log.Fatal",
+	"log.Fatalln",
+	"log.Fatalf",
+	"log.Panic",
+	"log.Panicln",
+	"log.Panicf",
+	"(*log.Logger).Print",
+	"(*log.Logger).Println",
+	"(*log.Logger).Printf",
+	"(*log.Logger).Fatal",
+	"(*log.Logger).Fatalln",
+	"(*log.Logger).Fatalf",
+	"(*log.Logger).Panic",
+	"(*log.Logger).Panicln",
+	"(*log.Logger).Panicf",
+}
+
+// sinkSet returns the names of the reflection sinks, as configured.
+func (c *Checker) sinkSet() map[string]bool {
+	if c.sinks != nil {
+		return c.sinks
+	}
+	names := c.Sinks
+	if len(names) == 0 {
+		names = DefaultSinks
+	} else if len(names) == 1 && names[0] == "none" {
+		names = nil
+	}
+	c.sinks = make(map[string]bool, len(names))
+	for _, name := range names {
+		c.sinks[name] = true
+	}
+	return c.sinks
+}
+
+// reachedSink returns the name of the reflection sink called by ce, if
+// its i-th argument is observed by it. For variadic funcs, only the
+// variadic arguments are, like those printed by fmt.Fprint and not its
+// writer. For printf-like funcs, those formatted with %p or %T aren't,
+// if the format is constant.
+func (c *Checker) reachedSink(ce *ast.CallExpr, i int) string {
+	fn, _ := typeutil.Callee(c.Info, ce).(*types.Func)
+	if fn == nil {
+		return ""
+	}
+	name := fn.Origin().FullName()
+	if !c.sinkSet()[name] {
+		return ""
+	}
+	sign := fn.Type().(*types.Signature)
+	params := sign.Params()
+	n := params.Len()
+	if !sign.Variadic() {
+		return name
+	}
+	if i < n-1 {
+		return ""
+	}
+	if n < 2 || ce.Ellipsis.IsValid() {
+		return name
+	}
+	if basic, ok := params.At(n - 2).Type().(*types.Basic); !ok || basic.Kind() != types.String {
+		return name
+	}
+	format := c.Types[ce.Args[n-2]].Value
+	if format == nil || format.Kind() != constant.String {
+		return name
+	}
+	if !reflectVerb(constant.StringVal(format), i-(n-1)) {
+		return ""
+	}
+	return name
+}
+
+// reflectVerb reports whether the argument at index arg is formatted by
+// format with a verb which walks its value through reflection, which is
+// any but %p and %T. Those print its address and the name of its type,
+// which don't change when narrowing it. Formats using explicit argument
+// indexes or star widths are assumed to.
+func reflectVerb(format string, arg int) bool {
+	n := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i == len(format) {
+			break
+		}
+		switch format[i] {
+		case '%':
+			continue
+		case '[', '*':
+			return true
+		}
+		if n == arg {
+			return format[i] != 'p' && format[i] != 'T'
+		}
+		n++
+	}
+	// extra arguments are printed with their types
+	return true
+}
+
+// sinkOf returns the reflection sink reached by a variable or by those
+// it's assigned to, if any.
+func sinkOf(usage *varUsage) string {
+	if usage.sink != "" {
+		return usage.sink
+	}
+	for to := range usage.assigned {
+		if sink := sinkOf(to); sink != "" {
+			return sink
+		}
+	}
+	return ""
+}
+
+// markSink notes on issue that the variable with the given usage
+// reaches a reflection sink.
+func markSink(issue *Issue, usage *varUsage) {
+	if issue.Sink = sinkOf(usage); issue.Sink != "" {
+		issue.msg += ", though it's observed through reflection by " + issue.Sink
+	}
+}
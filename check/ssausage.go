@@ -0,0 +1,295 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// ssaUsage is a second pass over the params of fd, after the walk of
+// its AST. It follows each param through the SSA form of fd, recording
+// the methods called on it and discarding it if it's used in a way that
+// needs its type, like being stored in memory of that type or returned
+// as it. Values are followed through phi nodes, the locals they are
+// stored in, the closures capturing them and their conversions to
+// interfaces.
+//
+// Whether a param can be narrowed depends on the types of the variables
+// declared from it, which follow from their declarations rather than
+// from the values they hold: after "x := f; x = os.Stdout", returning x
+// still needs the type of f. SSA only has the values, so the AST walk
+// makes the decisions, and this pass only adds the uses it missed.
+func (c *Checker) ssaUsage(fd *funcDecl) {
+	fn := fd.ssaFn
+	if fn == nil {
+		fn = c.ssaByPos[fd.ftype.Func]
+	}
+	if fn == nil {
+		return
+	}
+	for _, param := range fn.Params {
+		vr, ok := param.Object().(*types.Var)
+		if !ok {
+			continue
+		}
+		usage := c.vars[vr]
+		if usage == nil || usage.discard {
+			continue
+		}
+		fl := &ssaFlow{
+			c:     c,
+			fn:    fn,
+			param: param,
+			usage: usage,
+			seen:  make(map[ssa.Value]bool),
+		}
+		fl.value(param)
+	}
+}
+
+// ssaFlow follows the values of a param.
+type ssaFlow struct {
+	c     *Checker
+	fn    *ssa.Function
+	param *ssa.Parameter
+	usage *varUsage
+	seen  map[ssa.Value]bool
+}
+
+func (fl *ssaFlow) discard() {
+	fl.usage.discard = true
+}
+
+func (fl *ssaFlow) usedAs(t types.Type) {
+	iface, ok := t.Underlying().(*types.Interface)
+	if !ok {
+		fl.discard()
+		return
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		fl.usage.calls[iface.Method(i).Name()] = struct{}{}
+	}
+}
+
+// value follows v, which holds the param.
+func (fl *ssaFlow) value(v ssa.Value) {
+	if fl.seen[v] {
+		return
+	}
+	fl.seen[v] = true
+	for _, instr := range *v.Referrers() {
+		if fl.usage.discard {
+			return
+		}
+		switch x := instr.(type) {
+		case *ssa.DebugRef:
+		case *ssa.Phi:
+			fl.value(x)
+		case *ssa.MakeInterface:
+			fl.usedAs(x.Type())
+		case *ssa.ChangeInterface:
+			fl.usedAs(x.Type())
+		case *ssa.TypeAssert:
+			// v is an interface already, and stays one
+		case *ssa.BinOp:
+			// comparisons with nil are dealt with in the AST
+			if x.Op != token.EQL && x.Op != token.NEQ {
+				fl.discard()
+			}
+		case ssa.CallInstruction:
+			fl.call(v, x.Common())
+		case *ssa.Store:
+			if x.Val != v {
+				// storing through v
+				fl.discard()
+			} else {
+				fl.store(x.Addr)
+			}
+		case *ssa.MakeClosure:
+			fl.bindings(v, x)
+		case *ssa.UnOp, *ssa.FieldAddr, *ssa.Field:
+			if !fl.receiver(x.(ssa.Value)) {
+				fl.discard()
+			}
+		default:
+			fl.discard()
+		}
+	}
+}
+
+// store follows the param stored at addr. Only the local variables
+// it's assigned to are followed, as any other memory has its type.
+func (fl *ssaFlow) store(addr ssa.Value) {
+	switch x := addr.(type) {
+	case *ssa.Alloc:
+		fl.variable(x)
+	case *ssa.FieldAddr:
+		ptr, _ := x.X.Type().Underlying().(*types.Pointer)
+		if ptr == nil {
+			fl.discard()
+			break
+		}
+		st, _ := ptr.Elem().Underlying().(*types.Struct)
+		if st != nil && fl.c.fields[st.Field(x.Field)] != nil {
+			// a struct field which may be narrowed too, as
+			// found when walking the AST
+			return
+		}
+		fl.discard()
+	default:
+		fl.discard()
+	}
+}
+
+// variable follows the local variable at addr, which holds the param.
+func (fl *ssaFlow) variable(addr ssa.Value) {
+	if fl.seen[addr] {
+		return
+	}
+	fl.seen[addr] = true
+	for _, instr := range *addr.Referrers() {
+		if fl.usage.discard {
+			return
+		}
+		switch x := instr.(type) {
+		case *ssa.DebugRef:
+		case *ssa.Store:
+			// assigning to the variable, which the AST walk
+			// accounts for
+			if x.Addr != addr {
+				fl.discard()
+			}
+		case *ssa.UnOp:
+			if x.Op != token.MUL {
+				fl.discard()
+				break
+			}
+			fl.value(x)
+		case *ssa.MakeClosure:
+			fl.bindings(addr, x)
+		case ssa.CallInstruction:
+			// a method with a pointer receiver called on an
+			// addressable value
+			fl.call(addr, x.Common())
+		case *ssa.FieldAddr:
+			// likewise, but promoted from an embedded field
+			if !fl.receiver(x) {
+				fl.discard()
+			}
+		default:
+			fl.discard()
+		}
+	}
+}
+
+// bindings follows v into the closures capturing it. Captured variables
+// are bound by address.
+func (fl *ssaFlow) bindings(v ssa.Value, mc *ssa.MakeClosure) {
+	fn := mc.Fn.(*ssa.Function)
+	for i, b := range mc.Bindings {
+		if b == v {
+			fl.variable(fn.FreeVars[i])
+		}
+	}
+}
+
+// call records v being passed to a call.
+func (fl *ssaFlow) call(v ssa.Value, common *ssa.CallCommon) {
+	if common.IsInvoke() && common.Value == v {
+		fl.usage.calls[common.Method.Name()] = struct{}{}
+	} else if common.Value == v {
+		// calling a func
+		fl.discard()
+		return
+	}
+	if _, ok := common.Value.(*ssa.Builtin); ok {
+		fl.discard()
+		return
+	}
+	sign := common.Signature()
+	callee := common.StaticCallee()
+	for i, arg := range common.Args {
+		if arg != v {
+			continue
+		}
+		j := i
+		if !common.IsInvoke() && sign.Recv() != nil {
+			// a static method call, whose args start with the
+			// receiver; those of invoke calls never hold it
+			if i == 0 {
+				// method call on v, or on what's promoted from
+				// its embedded fields
+				if callee != nil {
+					fl.usage.calls[callee.Name()] = struct{}{}
+				}
+				continue
+			}
+			j--
+		}
+		if callee != nil && callee.Origin() == fl.fn.Origin() && i < len(fl.fn.Params) && fl.fn.Params[i] == fl.param {
+			// passed as itself in a recursive call
+			continue
+		}
+		param := sign.Params().At(j)
+		if fl.c.Interprocedural && callee != nil && !types.IsInterface(param.Type()) {
+			// may be narrowed along with the callee's param
+			continue
+		}
+		fl.usedAs(param.Type())
+	}
+}
+
+// receiver reports whether v, a field of the param or what it points
+// to, is only used as the receiver of method calls, which is how
+// promoted methods and those with value receivers are called on it. The
+// methods are recorded as called.
+func (fl *ssaFlow) receiver(v ssa.Value) bool {
+	if x, ok := v.(*ssa.UnOp); ok && x.Op != token.MUL {
+		return false
+	}
+	refs := *v.Referrers()
+	if len(refs) == 0 {
+		return false
+	}
+	var names []string
+	for _, instr := range refs {
+		switch x := instr.(type) {
+		case *ssa.DebugRef:
+			continue
+		case *ssa.UnOp, *ssa.FieldAddr, *ssa.Field:
+			if !fl.receiver(x.(ssa.Value)) {
+				return false
+			}
+			continue
+		case ssa.CallInstruction:
+			common := x.Common()
+			if common.IsInvoke() {
+				if common.Value != v {
+					return false
+				}
+				names = append(names, common.Method.Name())
+				continue
+			}
+			callee := common.StaticCallee()
+			if callee == nil || callee.Signature.Recv() == nil || len(common.Args) == 0 || common.Args[0] != v {
+				return false
+			}
+			for _, arg := range common.Args[1:] {
+				if arg == v {
+					return false
+				}
+			}
+			names = append(names, callee.Name())
+			continue
+		}
+		return false
+	}
+	for _, name := range names {
+		fl.usage.calls[name] = struct{}{}
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// synthesize proposes a new interface holding the methods called on
+// param, for when no interface in scope has exactly those.
+func (c *Checker) synthesize(param *types.Var, called map[string]string) *types.TypeName {
+	key := funcMapString(called)
+	if tn := c.synthBySet[key]; tn != nil {
+		return tn
+	}
+	names := make([]string, 0, len(called))
+	for name := range called {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pkg := c.pkg
+	signs := make([]*types.Signature, len(names))
+	for i, name := range names {
+		obj, _, _ := types.LookupFieldOrMethod(param.Type(), true, param.Pkg(), name)
+		fn, ok := obj.(*types.Func)
+		if !ok {
+			return nil
+		}
+		signs[i] = fn.Type().(*types.Signature)
+		if mentionsTypeParam(signs[i], nil) {
+			// can't be declared outside of the generic func or type
+			return nil
+		}
+		if mentionsUnexported(signs[i], c.pkg) {
+			// can only be declared next to the param's type
+			named := typeNamed(param.Type())
+			if named == nil || named.Obj().Pkg() == nil {
+				return nil
+			}
+			pkg = named.Obj().Pkg()
+		}
+	}
+	funcs := make([]*types.Func, len(names))
+	for i, name := range names {
+		sign := types.NewSignatureType(nil, nil, nil,
+			signs[i].Params(), signs[i].Results(), signs[i].Variadic())
+		funcs[i] = types.NewFunc(token.NoPos, pkg, name, sign)
+	}
+	tname := c.unusedName(pkg, ifaceNameFor(names))
+	tn := types.NewTypeName(token.NoPos, pkg, tname, nil)
+	types.NewNamed(tn, types.NewInterfaceType(funcs, nil).Complete(), nil)
+	c.synthBySet[key] = tn
+	c.synthesized[tn] = true
+	return tn
+}
+
+// unusedName returns name, or name followed by a number if the former
+// is already declared in pkg or proposed for it.
+func (c *Checker) unusedName(pkg *types.Package, name string) string {
+	taken := func(name string) bool {
+		if pkg.Scope().Lookup(name) != nil {
+			return true
+		}
+		for tn := range c.synthesized {
+			if tn.Pkg() == pkg && tn.Name() == name {
+				return true
+			}
+		}
+		return false
+	}
+	try := name
+	for i := 2; taken(try); i++ {
+		try = fmt.Sprintf("%s%d", name, i)
+	}
+	return try
+}
+
+// mentionsUnexported reports whether t refers to an unexported named
+// type which isn't accessible from the given package.
+func mentionsUnexported(t types.Type, from *types.Package) bool {
+	switch x := types.Unalias(t).(type) {
+	case *types.Named:
+		obj := x.Obj()
+		if !obj.Exported() && obj.Pkg() != nil && obj.Pkg() != from {
+			return true
+		}
+		args := x.TypeArgs()
+		for i := 0; i < args.Len(); i++ {
+			if mentionsUnexported(args.At(i), from) {
+				return true
+			}
+		}
+	case *types.Pointer:
+		return mentionsUnexported(x.Elem(), from)
+	case *types.Slice:
+		return mentionsUnexported(x.Elem(), from)
+	case *types.Array:
+		return mentionsUnexported(x.Elem(), from)
+	case *types.Chan:
+		return mentionsUnexported(x.Elem(), from)
+	case *types.Map:
+		return mentionsUnexported(x.Key(), from) || mentionsUnexported(x.Elem(), from)
+	case *types.Tuple:
+		for i := 0; i < x.Len(); i++ {
+			if mentionsUnexported(x.At(i).Type(), from) {
+				return true
+			}
+		}
+	case *types.Signature:
+		return mentionsUnexported(x.Params(), from) || mentionsUnexported(x.Results(), from)
+	}
+	return false
+}
+
+// ifaceNameFor returns a name for an interface with the given methods,
+// following the io package: all but the last method as verbs, and the
+// last one as a noun, with Close always last. For example,
+// ReadWriteCloser.
+func ifaceNameFor(methods []string) string {
+	names := make([]string, 0, len(methods))
+	hasClose := false
+	for _, name := range methods {
+		if name == "Close" {
+			hasClose = true
+			continue
+		}
+		names = append(names, name)
+	}
+	if hasClose {
+		names = append(names, "Close")
+	}
+	last := len(names) - 1
+	names[last] = agentNoun(names[last])
+	return strings.Join(names, "")
+}
+
+func isVowel(b byte) bool {
+	return strings.IndexByte("aeiouAEIOU", b) >= 0
+}
+
+// agentNoun turns a verb into the noun for what does it, like Close into
+// Closer and Get into Getter.
+func agentNoun(verb string) string {
+	switch n := len(verb); {
+	case strings.HasSuffix(verb, "e"):
+		return verb + "r"
+	case n == 3 && !isVowel(verb[0]) && isVowel(verb[1]) &&
+		!isVowel(verb[2]) && strings.IndexByte("wxy", verb[2]) < 0:
+		return verb + verb[2:] + "er"
+	}
+	return verb + "er"
+}
+
+// ifaceDecl returns the source of the declaration for a synthesized
+// interface, or for one composed of others.
+func ifaceDecl(tn *types.TypeName) string {
+	iface := tn.Type().Underlying().(*types.Interface)
+	qf := types.RelativeTo(tn.Pkg())
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "type %s interface {\n", tn.Name())
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		fmt.Fprintf(&buf, "\t%s\n", types.TypeString(iface.EmbeddedType(i), qf))
+	}
+	for i := 0; i < iface.NumExplicitMethods(); i++ {
+		m := iface.ExplicitMethod(i)
+		fmt.Fprintf(&buf, "\t%s", m.Name())
+		types.WriteSignature(&buf, m.Type().(*types.Signature), qf)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
@@ -0,0 +1,5 @@
+package def // want package:"1 interfaces, 0 func signatures"
+
+type Closer interface {
+	Close()
+}
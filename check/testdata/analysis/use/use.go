@@ -0,0 +1,16 @@
+package use // want package:"1 interfaces, 0 func signatures"
+
+import "analysis/def"
+
+type ReadCloser interface {
+	def.Closer
+	Read()
+}
+
+func Basic(c def.Closer) {
+	c.Close()
+}
+
+func BasicWrong(rc ReadCloser) { // want "rc can be analysis/def.Closer"
+	rc.Close()
+}
@@ -0,0 +1,45 @@
+package catalog
+
+type T struct{}
+
+func (t *T) Read(p []byte) (int, error)                   { return 0, nil }
+func (t *T) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+func (t *T) Close() error                                 { return nil }
+func (t *T) String() string                               { return "" }
+func (t *T) Error() string                                { return "" }
+func (t *T) Lock()                                        {}
+func (t *T) Unlock()                                      {}
+func (t *T) MarshalText() ([]byte, error)                 { return nil, nil }
+
+func Read(t *T) { // WARN t can be io.ReadCloser
+	t.Read(nil)
+	t.Close()
+}
+
+func Str(t *T) string { // WARN t can be fmt.Stringer
+	return t.String()
+}
+
+func Err(t *T) string { // WARN t can be error
+	return t.Error()
+}
+
+func Locked(t *T) { // WARN t can be sync.Locker
+	t.Lock()
+	t.Unlock()
+}
+
+func Text(t *T) { // WARN t can be encoding.TextMarshaler
+	t.MarshalText()
+}
+
+func Rewind(t *T) { // WARN t can be io.ReadSeekCloser
+	t.Seek(0, 0)
+	t.Read(nil)
+	t.Close()
+}
+
+func Other(t *T) {
+	t.String()
+	t.Close()
+}
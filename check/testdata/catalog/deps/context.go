@@ -0,0 +1,17 @@
+package deps
+
+import "time"
+
+type Ctx struct{}
+
+func (c *Ctx) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (c *Ctx) Done() <-chan struct{}       { return nil }
+func (c *Ctx) Err() error                  { return nil }
+func (c *Ctx) Value(key any) any           { return nil }
+
+func Wait(c *Ctx) error { // WARN c can be context.Context
+	c.Deadline()
+	c.Value(nil)
+	<-c.Done()
+	return c.Err()
+}
@@ -0,0 +1,3 @@
+module deps
+
+go 1.21
@@ -0,0 +1,19 @@
+package old
+
+type T struct{}
+
+func (t *T) Read(p []byte) (int, error)                   { return 0, nil }
+func (t *T) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+func (t *T) Close() error                                 { return nil }
+
+func Read(t *T) { // WARN t can be io.ReadCloser
+	t.Read(nil)
+	t.Close()
+}
+
+// io.ReadSeekCloser was added in Go 1.16
+func Rewind(t *T) {
+	t.Seek(0, 0)
+	t.Read(nil)
+	t.Close()
+}
@@ -0,0 +1,52 @@
+package compose
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type Closer interface {
+	Close() error
+}
+
+type Flusher interface {
+	Flush() error
+}
+
+type Pinger interface {
+	Ping() error
+}
+
+type ReadFlusher interface {
+	Read(p []byte) (int, error)
+	Flush() error
+}
+
+type Conn struct{}
+
+func (c *Conn) Read(p []byte) (int, error) { return 0, nil }
+func (c *Conn) Close() error               { return nil }
+func (c *Conn) Flush() error               { return nil }
+func (c *Conn) Ping() error                { return nil }
+func (c *Conn) Reset()                     {}
+
+func Shut(c *Conn) {
+	c.Read(nil)
+	c.Close()
+}
+
+func Drain(c *Conn) {
+	c.Read(nil)
+	c.Flush()
+	c.Close()
+}
+
+func Check(c *Conn) {
+	c.Ping()
+	c.Flush()
+	c.Close()
+}
+
+func Reset(c *Conn) {
+	c.Close()
+	c.Reset()
+}
@@ -0,0 +1,11 @@
+package a
+
+import "depth/b"
+
+var _ b.Flusher
+
+var Unused int
+
+type Sizer interface {
+	Size() int64
+}
@@ -0,0 +1,9 @@
+package b
+
+import "depth/c"
+
+var _ c.Closer
+
+type Flusher interface {
+	Flush() error
+}
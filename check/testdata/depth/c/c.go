@@ -0,0 +1,5 @@
+package c
+
+type Closer interface {
+	Close() error
+}
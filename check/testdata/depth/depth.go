@@ -0,0 +1,23 @@
+package depth
+
+import "depth/a"
+
+var _ = a.Unused
+
+type T struct{}
+
+func (t *T) Close() error { return nil }
+func (t *T) Flush() error { return nil }
+func (t *T) Size() int64  { return 0 }
+
+func Shut(t *T) {
+	t.Close()
+}
+
+func Sync(t *T) {
+	t.Flush()
+}
+
+func Measure(t *T) int64 {
+	return t.Size()
+}
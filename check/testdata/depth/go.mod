@@ -0,0 +1,3 @@
+module depth
+
+go 1.25.0
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"os"
+
+	"escape/lib"
+)
+
+var handlers []func(*os.File) error
+
+func register(h func(*os.File) error) {
+	handlers = append(handlers, h)
+}
+
+var shut = (&lib.Conn{}).Shut
+
+func init() {
+	register(lib.Handle)
+}
+
+func Run(c *lib.Conn, f *os.File) error {
+	if err := lib.Other(f); err != nil {
+		return err
+	}
+	return c.Stop(f)
+}
+
+type sender interface {
+	Send(f *os.File) error
+}
+
+func Notify(s sender, f *os.File) error {
+	return s.Send(f)
+}
+
+func Ping(c *lib.Conn, f *os.File) error {
+	return Notify(c, f)
+}
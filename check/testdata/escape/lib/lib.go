@@ -0,0 +1,26 @@
+package lib
+
+import "os"
+
+func Handle(f *os.File) error {
+	return f.Close()
+}
+
+func Other(f *os.File) error {
+	return f.Close()
+}
+
+type Conn struct{}
+
+func (c *Conn) Shut(f *os.File) error {
+	return f.Close()
+}
+
+func (c *Conn) Stop(f *os.File) error {
+	return f.Close()
+}
+
+// Send is only called through an interface declared in app.
+func (c *Conn) Send(f *os.File) error {
+	return f.Close()
+}
@@ -13,19 +13,19 @@ type ReadCloser interface {
 	Closer
 }
 
-func CompareNil(rc ReadCloser) { // WARN rc can be Closer
+func CompareNil(rc ReadCloser) { // WARN rc can be io.Closer
 	if rc != nil {
 		rc.Close()
 	}
 }
 
-func CompareIface(rc ReadCloser) { // WARN rc can be Closer
+func CompareIface(rc ReadCloser) { // WARN rc can be io.Closer
 	if rc != ReadCloser(nil) {
 		rc.Close()
 	}
 }
 
-func CompareIfaceDiff(rc ReadCloser) { // WARN rc can be Closer
+func CompareIfaceDiff(rc ReadCloser) { // WARN rc can be io.Closer
 	if rc != Reader(nil) {
 		rc.Close()
 	}
@@ -37,13 +37,13 @@ func (m mint) Close() error {
 	return nil
 }
 
-func CompareStruct(m mint) { // WARN m can be Closer
+func CompareStruct(m mint) { // WARN m can be io.Closer
 	if m != mint(3) {
 		m.Close()
 	}
 }
 
-func CompareStructVar(m mint) { // WARN m can be Closer
+func CompareStructVar(m mint) { // WARN m can be io.Closer
 	m2 := mint(2)
 	if m == m2 {
 		m.Close()
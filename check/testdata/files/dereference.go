@@ -8,7 +8,7 @@ type st struct{}
 
 func (s *st) Close() {}
 
-func Wrong(s st) { // WARN s can be Closer
+func Wrong(s st) {
 	s.Close()
 	s = st{}
 }
@@ -0,0 +1,107 @@
+package foo
+
+import "os"
+
+func Returned(f *os.File) *os.File {
+	f.Close()
+	return f
+}
+
+func ReturnedWithErr(f *os.File) (*os.File, error) {
+	return f, f.Close()
+}
+
+func ReturnedAsIface(f *os.File) (interface{ Close() error }, error) { // WARN f can be io.Closer
+	return f, nil
+}
+
+func ReturnedInLit(f *os.File) func() *os.File {
+	f.Close()
+	return func() *os.File {
+		return f
+	}
+}
+
+func ReturnedFromLit(f *os.File) error { // WARN f can be io.Closer
+	get := func() error {
+		return f.Close()
+	}
+	return get()
+}
+
+func Sent(f *os.File, ch chan<- *os.File) {
+	f.Close()
+	ch <- f
+}
+
+func MapKey(f *os.File, m map[*os.File]int) int {
+	f.Close()
+	return m[f]
+}
+
+func MapKeyAssigned(f *os.File, m map[*os.File]int) {
+	f.Close()
+	m[f] = 3
+}
+
+func MapLitKey(f *os.File) map[*os.File]bool {
+	f.Close()
+	return map[*os.File]bool{f: true}
+}
+
+func SliceLitIndexed(f *os.File) []*os.File {
+	f.Close()
+	return []*os.File{2: f}
+}
+
+func RangedInto(f *os.File, fs []*os.File) { // WARN f can be io.Closer
+	for _, f = range fs {
+		f.Close()
+	}
+}
+
+type files []*os.File
+
+func (fs files) Close() error { return nil }
+func (fs files) Sync() error  { return nil }
+
+func RangedOver(fs files) {
+	fs.Close()
+	for range fs {
+	}
+}
+
+func Sliced(fs files) files {
+	fs.Close()
+	return fs[1:]
+}
+
+type counter int
+
+func (c counter) Close() error { return nil }
+func (c counter) Sync() error  { return nil }
+
+func AddAssigned(c counter) {
+	c.Close()
+	c += 2
+}
+
+func MethodValue(f *os.File) func() error { // WARN f can be io.Closer
+	g := f.Close
+	return g
+}
+
+func MethodValueOther(f *os.File) {
+	f.Close()
+	g := f.Stat
+	g()
+}
+
+func MethodExpr(f *os.File) {
+	f.Close()
+	(*os.File).Close(f)
+}
+
+func Parens(f *os.File) { // WARN f can be io.Closer
+	(f).Close()
+}
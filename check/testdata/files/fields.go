@@ -22,7 +22,7 @@ func Foo(s *st) {
 	s.field = 3
 }
 
-func FooWrong(s *st) { // WARN s can be Closer
+func FooWrong(s *st) { // WARN s can be io.Closer
 	s.Close()
 }
 
@@ -39,7 +39,7 @@ func Foo2(s *st2) {
 	s.st1.field = 3
 }
 
-func Foo2Wrong(s *st2) { // WARN s can be Closer
+func Foo2Wrong(s *st2) { // WARN s can be io.Closer
 	s.Close()
 }
 
@@ -49,7 +49,7 @@ func FooPassed(s *st) {
 	s2.field = 2
 }
 
-func FooPassedWrong(s *st) { // WARN s can be Closer
+func FooPassedWrong(s *st) { // WARN s can be io.Closer
 	s.Close()
 	s2 := s
 	s2.Close()
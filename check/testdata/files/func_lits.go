@@ -0,0 +1,96 @@
+package foo
+
+type Closer interface {
+	Close() error
+}
+
+type st struct{}
+
+func (s *st) Close() error { return nil }
+func (s *st) Other()       {}
+
+type CloseFunc func(s *st, n int)
+
+func takeFunc(f func(s *st)) {}
+
+func returnFunc() func(s *st) {
+	return func(s *st) {
+		s.Close()
+	}
+}
+
+var global = func(s *st) { // WARN s can be io.Closer
+	s.Close()
+}
+
+var Exported = func(s *st) {
+	s.Close()
+}
+
+func Called() {
+	func(s *st) { // WARN s can be io.Closer
+		s.Close()
+	}(nil)
+}
+
+func Bound() {
+	f := func(s *st) { // WARN s can be io.Closer
+		s.Close()
+	}
+	f(nil)
+	f(nil)
+}
+
+func BoundPassed() {
+	f := func(s *st) {
+		s.Close()
+	}
+	takeFunc(f)
+}
+
+func BoundReassigned() {
+	f := func(s *st) {
+		s.Close()
+	}
+	f = func(s *st) {}
+	f(nil)
+}
+
+func Passed() {
+	takeFunc(func(s *st) {
+		s.Close()
+	})
+}
+
+func Typed() {
+	var f CloseFunc = func(s *st, n int) {
+		s.Close()
+	}
+	f(nil, 0)
+	g := CloseFunc(func(s *st, n int) {
+		s.Close()
+	})
+	g(nil, 0)
+}
+
+func UsedMore() {
+	f := func(s *st) {
+		s.Close()
+		s.Other()
+	}
+	f(nil)
+}
+
+func (s *st) Nested() {
+	func() {
+		func(s *st) { // WARN s can be io.Closer
+			s.Close()
+		}(nil)
+	}()
+}
+
+func Generic[T any]() {
+	func(s *st) { // WARN s can be io.Closer
+		s.Close()
+	}(nil)
+}
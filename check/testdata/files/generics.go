@@ -0,0 +1,98 @@
+package foo
+
+type Getter[K comparable, V any] interface {
+	Get(k K) (V, bool)
+}
+
+type Closer interface {
+	Close() error
+}
+
+type ReadCloser interface {
+	Read(p []byte) (int, error)
+	Close() error
+}
+
+type Cache[K comparable, V any] struct {
+	m map[K]V
+}
+
+func (c *Cache[K, V]) Get(k K) (V, bool) {
+	v, ok := c.m[k]
+	return v, ok
+}
+
+func (c *Cache[K, V]) Set(k K, v V) {
+	c.m[k] = v
+}
+
+func Lookup[K comparable, V any](c *Cache[K, V], k K) V { // WARN c can be Getter[K, V]
+	v, _ := c.Get(k)
+	return v
+}
+
+func LookupString(c *Cache[string, int]) int { // WARN c can be Getter[string, int]
+	v, _ := c.Get("foo")
+	return v
+}
+
+func Update[K comparable, V any](c *Cache[K, V], k K, v V) {
+	c.Get(k)
+	c.Set(k, v)
+}
+
+func CloseAll[T ReadCloser](xs []T) { // WARN T can be io.Closer
+	for _, x := range xs {
+		x.Close()
+	}
+}
+
+func ReadClose[T ReadCloser](x T) {
+	x.Read(nil)
+	x.Close()
+}
+
+func CloseVia[T ReadCloser](x T) { // WARN T can be io.Closer
+	closeIt(x)
+}
+
+func closeIt[U Closer](u U) {
+	u.Close()
+}
+
+func ReadVia[T ReadCloser](x T) {
+	readClose(x)
+}
+
+func readClose[U ReadCloser](u U) {
+	u.Read(nil)
+	u.Close()
+}
+
+func Converted[T ReadCloser](x T) {
+	var rc ReadCloser = x
+	rc.Close()
+}
+
+func MethodValue[T ReadCloser](x T) { // WARN T can be io.Closer
+	f := x.Close
+	f()
+}
+
+type Box[T any] struct {
+	v T
+}
+
+func Wrap[T any](x T) Box[T] {
+	return Box[T]{x}
+}
+
+type conn struct{}
+
+func (c *conn) Close() error { return nil }
+func (c *conn) Other()       {}
+
+func Inferred(rc ReadCloser) Box[ReadCloser] {
+	rc.Close()
+	return Wrap(rc)
+}
@@ -11,11 +11,18 @@ type ReadCloser interface {
 
 type MyFunc func(rc ReadCloser, err error) bool
 
-func MyFuncImpl(rc ReadCloser, err error) bool {
+func MyFuncImpl(rc ReadCloser, err error) bool { // WARN rc can be Closer
 	rc.Close()
 	return false
 }
 
+func MyFuncAssigned(rc ReadCloser, err error) bool {
+	rc.Close()
+	return false
+}
+
+var _ MyFunc = MyFuncAssigned
+
 func MyFuncWrong(rc ReadCloser, err error) { // WARN rc can be Closer
 	rc.Close()
 }
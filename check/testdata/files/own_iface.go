@@ -19,7 +19,7 @@ func Foo(s St) { // WARN s can be Barer
 	_ = s.Bar(nil)
 }
 
-func Bar(fc FooCloser) int {
+func Bar(fc FooCloser) int { // WARN fc can be io.Closer
 	fc.Close()
 	return 3
 }
@@ -0,0 +1,39 @@
+package foo
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+type Buffered struct {
+	*bytes.Buffer
+}
+
+func Greet(b *Buffered) { // WARN b can be io.Writer
+	b.Write([]byte("hi"))
+}
+
+type Guarded struct {
+	sync.Mutex
+	n int
+}
+
+func Guard(g *Guarded) { // WARN g can be sync.Locker
+	g.Lock()
+	g.Unlock()
+}
+
+type Closing struct {
+	io.ReadCloser
+}
+
+func Shut(c Closing) { // WARN c can be io.Closer
+	c.Close()
+}
+
+func Count(g *Guarded) int {
+	g.Lock()
+	defer g.Unlock()
+	return g.n
+}
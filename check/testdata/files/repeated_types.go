@@ -13,7 +13,7 @@ func (s st) Name() string {
 type MyPathFunc func(path string, s st) error
 type MyPathFunc2 func(path string, s st) error
 
-func Impl(path string, s st) error {
+func Impl(path string, s st) error { // WARN s can be Namer
 	s.Name()
 	return nil
 }
@@ -9,7 +9,7 @@ type FooCloser interface {
 	Close() error
 }
 
-func ShadowArg(fc FooCloser) { // WARN fc can be Closer
+func ShadowArg(fc FooCloser) { // WARN fc can be io.Closer
 	fc.Close()
 	for {
 		fc := 3
@@ -0,0 +1,136 @@
+package foo
+
+import (
+	"io"
+	"os"
+)
+
+func Merged(f *os.File, g *os.File, both bool) { // WARN f can be io.Closer
+	x := f
+	if both {
+		x = g
+	}
+	x.Close()
+}
+
+func MergedReturned(f *os.File, other bool) *os.File {
+	x := f
+	if other {
+		x = nil
+	}
+	x.Close()
+	return x
+}
+
+func Captured(f *os.File) func() error { // WARN f can be io.Closer
+	return func() error {
+		return f.Close()
+	}
+}
+
+func CapturedStored(f *os.File, fs []*os.File) {
+	f.Close()
+	func() {
+		fs[0] = f
+	}()
+}
+
+func CapturedReassigned(f *os.File) func() *os.File {
+	f.Close()
+	return func() *os.File {
+		g := f
+		return g
+	}
+}
+
+func Converted(f *os.File) { // WARN f can be io.ReadCloser
+	var c io.Closer = f
+	c.Close()
+	f.Read(nil)
+}
+
+func ConvertedAny(f *os.File) { // WARN f can be io.Closer
+	f.Close()
+	println(any(f) != nil)
+}
+
+type wrapped struct {
+	*os.File
+}
+
+func PromotedCall(w wrapped) { // WARN w can be io.Closer
+	w.Close()
+}
+
+func PromotedField(w wrapped) {
+	w.File.Close()
+}
+
+func PromotedValue(w wrapped) *os.File {
+	w.Close()
+	return w.File
+}
+
+var lit = func(f *os.File) { // WARN f can be io.Closer
+	f.Close()
+}
+
+var litStored = func(f *os.File, fs []*os.File) {
+	f.Close()
+	fs[0] = f
+}
+
+func UntypedVar(f *os.File) *os.File {
+	f.Close()
+	var x = f
+	return x
+}
+
+func ParenElem(f *os.File) []*os.File {
+	f.Close()
+	return []*os.File{(f)}
+}
+
+func Swapped(f, g *os.File) (*os.File, *os.File) {
+	f.Close()
+	g.Close()
+	x, y := f, g
+	x, y = y, x
+	return x, y
+}
+
+type Taker interface {
+	Take(*os.File)
+}
+
+func TakenThroughIface(t Taker, f *os.File) {
+	f.Close()
+	g := f
+	t.Take(g)
+}
+
+func Reassigned(f *os.File) *os.File {
+	f.Close()
+	f = os.Stdout
+	return f
+}
+
+func DefinedLocal(f *os.File) *os.File {
+	f.Close()
+	x := f
+	x = os.Stdout
+	return x
+}
+
+func UntypedLocal(f *os.File) *os.File {
+	f.Close()
+	var x = f
+	x = os.Stdout
+	return x
+}
+
+func UntypedLocalCalled(f *os.File) { // WARN f can be io.ReadCloser
+	f.Close()
+	var x = f
+	x.Read(nil)
+}
@@ -0,0 +1,63 @@
+package foo
+
+type Closer interface {
+	Close() error
+}
+
+type ReadCloser interface {
+	Closer
+	Read() (int, error)
+}
+
+type conn struct{}
+
+func (c *conn) Close() error       { return nil }
+func (c *conn) Read() (int, error) { return 0, nil }
+func (c *conn) Write() error       { return nil }
+
+type server struct {
+	c   *conn
+	r   *conn // WARN r can be ReadCloser
+	w   *conn
+	ret *conn
+	Exp *conn
+	val conn
+}
+
+func newServer(c *conn) *server {
+	return &server{c: c}
+}
+
+func (s *server) setR(r *conn) { // WARN r can be ReadCloser
+	s.r = r
+}
+
+func (s *server) setRet(x *conn) {
+	s.ret = x
+}
+
+func (s *server) close() {
+	if s.c == nil {
+		return
+	}
+	s.c.Close()
+}
+
+func (s *server) read() {
+	s.r.Read()
+	s.r.Close()
+}
+
+func (s *server) write() {
+	s.w.Write()
+}
+
+func (s *server) getRet() *conn {
+	s.ret.Close()
+	return s.ret
+}
+
+func (s *server) closeOthers() {
+	s.Exp.Close()
+	s.val.Close()
+}
@@ -0,0 +1,65 @@
+package foo
+
+import (
+	"io"
+	"os"
+)
+
+func Guarded(f *os.File) {
+	if f != nil {
+		f.Close()
+	}
+}
+
+func EarlyReturn(f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	return f.Close()
+}
+
+func Reversed(f *os.File) {
+	if nil == f {
+		return
+	}
+	f.Close()
+}
+
+func Switched(f *os.File) {
+	switch f {
+	case nil:
+	default:
+		f.Close()
+	}
+}
+
+func Unchecked(f *os.File) { // WARN f can be io.Closer
+	f.Close()
+}
+
+func AlreadyIface(rc io.ReadCloser) { // WARN rc can be io.Closer
+	if rc == nil {
+		return
+	}
+	rc.Close()
+}
+
+func Alias(f *os.File) {
+	g := f
+	if g != nil {
+		g.Close()
+	}
+}
+
+func ReturnedAlias(f *os.File) io.Closer {
+	g := f
+	if g == nil {
+		return nil
+	}
+	return g
+}
+
+func ReturnedCheck(f *os.File) bool {
+	f.Close()
+	return f == nil
+}
@@ -11,7 +11,7 @@ type ReadCloser interface {
 
 type myFunc func(rc ReadCloser, err error) int
 
-func MyFuncImpl(rc ReadCloser, err error) int {
+func MyFuncImpl(rc ReadCloser, err error) int { // WARN rc can be Closer
 	rc.Close()
 	return 0
 }
@@ -24,7 +24,7 @@ type myIface interface {
 	Foo(rc ReadCloser, i int64)
 }
 
-func FooImpl(rc ReadCloser, i int64) {
+func FooImpl(rc ReadCloser, i int64) { // WARN rc can be Closer
 	rc.Close()
 }
 
@@ -10,7 +10,7 @@ func (s st) String() string {
 	return "foo"
 }
 
-func Exported(s st) string { // WARN s can be Stringer
+func Exported(s st) string { // WARN s can be fmt.Stringer
 	return s.String()
 }
 
@@ -36,14 +36,14 @@ func Bar(s St) {
 	FooSt(s)
 }
 
-func BarWrong(s St) { // WARN s can be Closer
+func BarWrong(s St) { // WARN s can be io.Closer
 	s.Close()
 	FooCloser(s)
 }
 
 func extra(n int, cs ...Closer) {}
 
-func ArgExtraWrong(s1 St) { // WARN s1 can be Closer
+func ArgExtraWrong(s1 St) { // WARN s1 can be io.Closer
 	var s2 St
 	s1.Close()
 	s2.Close()
@@ -63,14 +63,14 @@ func Declared(s St) {
 	_ = s2
 }
 
-func AssignedIface(s St) { // WARN s can be Closer
+func AssignedIface(s St) { // WARN s can be io.Closer
 	s.Close()
 	var c Closer
 	c = s
 	_ = c
 }
 
-func AssignedIfaceDiff(s St) { // WARN s can be ReadCloser
+func AssignedIfaceDiff(s St) { // WARN s can be io.ReadCloser
 	s.Close()
 	var r Reader
 	r = s
@@ -82,7 +82,7 @@ func doRead(r Reader) {
 	r.Read(b)
 }
 
-func ArgIfaceDiff(s St) { // WARN s can be ReadCloser
+func ArgIfaceDiff(s St) { // WARN s can be io.ReadCloser
 	s.Close()
 	doRead(s)
 }
@@ -0,0 +1,27 @@
+package foo
+
+type Conn struct{}
+
+func (c *Conn) Close() error { return nil }
+
+func (c *Conn) Dial() error { return nil }
+
+func ByValue(c Conn) {
+	c.Close()
+}
+
+func ByPointer(c *Conn) { // WARN c can be io.Closer
+	c.Close()
+}
+
+type Pair struct {
+	Conn
+}
+
+func Promoted(p Pair) {
+	p.Close()
+}
+
+func PromotedPointer(p *Pair) { // WARN p can be io.Closer
+	p.Close()
+}
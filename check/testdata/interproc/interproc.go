@@ -0,0 +1,84 @@
+package interproc
+
+import (
+	"os"
+
+	"interproc/store"
+)
+
+func Serve(f *os.File) error {
+	defer f.Close()
+	return Handle(f)
+}
+
+func Handle(f *os.File) error {
+	return parse(f)
+}
+
+func parse(f *os.File) error {
+	_, err := f.Read(nil)
+	return err
+}
+
+func Dump(f *os.File) error {
+	return store.Save(f)
+}
+
+var kept []*os.File
+
+func Keep(f *os.File) {
+	keep(f)
+}
+
+func keep(f *os.File) {
+	kept = append(kept, f)
+}
+
+func Ping(f *os.File) error {
+	return pong(f)
+}
+
+func pong(f *os.File) error {
+	if _, err := f.Read(nil); err != nil {
+		return err
+	}
+	return Ping(f)
+}
+
+func Handler(f *os.File, g *os.File) error {
+	return parseBoth(f, g)
+}
+
+func parseBoth(a, b *os.File) error {
+	if _, err := a.Read(nil); err != nil {
+		return err
+	}
+	return b.Chmod(0)
+}
+
+func Round(f *os.File) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return trip(f)
+}
+
+func trip(f *os.File) error {
+	return again(f)
+}
+
+func again(f *os.File) error {
+	if _, err := f.Read(nil); err != nil {
+		return err
+	}
+	return Round(f)
+}
+
+func Stash(f *os.File) error {
+	return stash(f)
+}
+
+func stash(f *os.File) error {
+	keep(f)
+	return Stash(f)
+}
@@ -0,0 +1,8 @@
+package store
+
+import "os"
+
+func Save(f *os.File) error {
+	_, err := f.Write([]byte("saved"))
+	return err
+}
@@ -1,4 +1,4 @@
-// +build go1.6
+//go:build go1.6
 
 package single
 
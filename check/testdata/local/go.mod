@@ -0,0 +1,5 @@
+module local
+
+go 1.25.0
+
+require foo/bar v0.0.0
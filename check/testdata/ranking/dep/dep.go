@@ -0,0 +1,16 @@
+package dep
+
+type Closer interface {
+	Close() error
+}
+
+// Drainer is something that can be drained.
+//
+// Deprecated: use Flusher instead.
+type Drainer interface {
+	Flush() error
+}
+
+type Flusher interface {
+	Flush() error
+}
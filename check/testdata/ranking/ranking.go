@@ -0,0 +1,42 @@
+package ranking
+
+import (
+	"io"
+
+	"ranking/dep"
+)
+
+type Closer interface {
+	Close() error
+}
+
+type Flusher interface {
+	Flush() error
+}
+
+type Stringer interface {
+	String() string
+}
+
+var _ io.Reader
+
+var _ dep.Closer
+
+type Conn struct{}
+
+func (c *Conn) Close() error   { return nil }
+func (c *Conn) Flush() error   { return nil }
+func (c *Conn) Reset()         {}
+func (c *Conn) String() string { return "" }
+
+func Shut(c *Conn) {
+	c.Close()
+}
+
+func Sync(c *Conn) {
+	c.Flush()
+}
+
+func Show(c *Conn) string {
+	return c.String()
+}
@@ -0,0 +1,17 @@
+package a
+
+import "os"
+
+type Conn struct{}
+
+func (c *Conn) Serve(f *os.File) error {
+	return f.Close()
+}
+
+func (c *Conn) Other(f *os.File) error {
+	return f.Close()
+}
+
+func Handle(f *os.File) error {
+	return f.Close()
+}
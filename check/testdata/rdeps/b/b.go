@@ -0,0 +1,19 @@
+package b
+
+import (
+	"os"
+
+	"rdeps/a"
+)
+
+type server interface {
+	Serve(f *os.File) error
+}
+
+func Run(s server, f *os.File) error {
+	return s.Serve(f)
+}
+
+func RunConn(f *os.File) error {
+	return Run(&a.Conn{}, f)
+}
@@ -0,0 +1,13 @@
+package c
+
+import (
+	"os"
+
+	"rdeps/a"
+)
+
+var handlers = []func(*os.File) error{a.Handle}
+
+func Handlers() []func(*os.File) error {
+	return handlers
+}
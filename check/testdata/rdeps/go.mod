@@ -0,0 +1,3 @@
+module rdeps
+
+go 1.25.0
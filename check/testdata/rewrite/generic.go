@@ -0,0 +1,26 @@
+package rewrite
+
+import "os"
+
+type Fetcher[K comparable, V any] interface {
+	Fetch(k K) (V, bool)
+}
+
+type Store[K comparable, V any] struct {
+	m map[K]V
+}
+
+func (s *Store[K, V]) Fetch(k K) (V, bool) {
+	v, ok := s.m[k]
+	return v, ok
+}
+
+func (s *Store[K, V]) Put(k K, v V) {
+	s.m[k] = v
+}
+
+// FetchFile fetches the file for name.
+func FetchFile(s *Store[string, *os.File], name string) *os.File {
+	f, _ := s.Fetch(name)
+	return f
+}
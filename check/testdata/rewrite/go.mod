@@ -0,0 +1,3 @@
+module rewrite
+
+go 1.25.0
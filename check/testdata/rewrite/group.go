@@ -0,0 +1,18 @@
+package rewrite
+
+import (
+	stdio "io"
+	"os" // only for File
+)
+
+var _ stdio.Writer
+
+func CloseBoth(a, b *os.File) {
+	a.Close()
+	b.Close()
+}
+
+func ReadAndClose(a, b *os.File, n int) {
+	a.Close()
+	b.Read(nil)
+}
@@ -0,0 +1,12 @@
+package rewrite
+
+import (
+	"fmt"
+	"os"
+)
+
+func ReadAll(f *os.File) {
+	var b [8]byte
+	f.Read(b[:])
+	fmt.Println(b)
+}
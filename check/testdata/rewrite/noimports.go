@@ -0,0 +1,11 @@
+package rewrite
+
+type St struct{}
+
+func (s *St) Close() error { return nil }
+
+func (s *St) Other() {}
+
+func Shutdown(s *St) {
+	s.Close()
+}
@@ -0,0 +1,8 @@
+package rewrite
+
+import "os"
+
+// CloseAll closes f.
+func CloseAll(f *os.File) {
+	f.Close()
+}
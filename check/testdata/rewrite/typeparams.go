@@ -0,0 +1,12 @@
+package rewrite
+
+type readCloser interface {
+	Read(p []byte) (int, error)
+	Close() error
+}
+
+func ReadBoth[A, B readCloser](x A, y B) {
+	x.Read(nil)
+	y.Read(nil)
+	y.Close()
+}
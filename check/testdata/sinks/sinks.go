@@ -0,0 +1,70 @@
+package foo
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+)
+
+func Marshaled(f *os.File) {
+	f.Close()
+	json.Marshal(f)
+}
+
+func Encoded(f *os.File, enc *json.Encoder) {
+	f.Close()
+	enc.Encode(f)
+}
+
+func Reflected(f *os.File) {
+	f.Close()
+	reflect.ValueOf(f)
+}
+
+func Assigned(f *os.File) {
+	f.Close()
+	g := f
+	reflect.TypeOf(g)
+}
+
+func Printed(f *os.File) {
+	f.Close()
+	fmt.Printf("%s: %+v\n", "file", f)
+}
+
+func PrintedType(f *os.File) {
+	f.Close()
+	_ = fmt.Sprintf("%d%% %T", 3, f)
+}
+
+func PrintedDynamic(f *os.File, format string) {
+	f.Close()
+	fmt.Printf(format, f)
+}
+
+func PrintedPointer(f *os.File) {
+	f.Close()
+	fmt.Printf("%v %p\n", 1, f)
+}
+
+func PrintedLine(f *os.File) {
+	f.Close()
+	fmt.Println("file:", f)
+}
+
+func Logged(f *os.File, l *log.Logger) {
+	f.Close()
+	l.Print(f)
+}
+
+func PrintedTo(f *os.File) {
+	f.Close()
+	fmt.Fprintln(f, "closed")
+}
+
+func PrintedString(f *os.File) {
+	f.Close()
+	fmt.Printf("%d: %s\n", 1, f)
+}
@@ -0,0 +1,3 @@
+module grab-import/def/nested
+
+go 1.25.0
@@ -0,0 +1,7 @@
+module grab-import
+
+go 1.25.0
+
+require grab-import/def/nested v0.0.0
+
+replace grab-import/def/nested => ./def/nested
@@ -7,7 +7,7 @@ import (
 
 type St struct{}
 
-func (s *St) Foo(rc def.ReadCloser, i int) int {
+func (s *St) Foo(rc def.ReadCloser, i int) int { // WARN rc can be io.Closer
 	rc.Close()
 	return def.SomeVar
 }
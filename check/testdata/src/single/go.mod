@@ -0,0 +1,3 @@
+module single
+
+go 1.25.0
@@ -0,0 +1,3 @@
+module superset
+
+go 1.25.0
@@ -0,0 +1,36 @@
+package superset
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+func Info(f *os.File) (fs.FileInfo, error) {
+	var b [1]byte
+	if _, err := f.Read(b[:]); err != nil {
+		return nil, err
+	}
+	return f.Stat()
+}
+
+func Exact(f *os.File) error {
+	return f.Close()
+}
+
+type statReader struct{}
+
+func (statReader) Read(p []byte) (int, error) { return 0, io.EOF }
+func (statReader) Stat() (fs.FileInfo, error) { return nil, nil }
+func (statReader) Close() error               { return nil }
+
+// the smallest superset has all the methods of the type
+func Same(sr statReader) (fs.FileInfo, error) {
+	sr.Read(nil)
+	return sr.Stat()
+}
+
+func AlreadyFile(f fs.File) (fs.FileInfo, error) {
+	f.Read(nil)
+	return f.Stat()
+}
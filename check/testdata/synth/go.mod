@@ -0,0 +1,3 @@
+module synth
+
+go 1.25.0
@@ -0,0 +1,9 @@
+package peer
+
+type frame struct{}
+
+type Peer struct{}
+
+func (p *Peer) Send(f *frame) error { return nil }
+func (p *Peer) Recv() *frame        { return nil }
+func (p *Peer) Close() error        { return nil }
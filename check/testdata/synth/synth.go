@@ -0,0 +1,28 @@
+package synth
+
+import "synth/peer"
+
+type Conn struct{}
+
+func (c *Conn) Flush() error          { return nil }
+func (c *Conn) Close() error          { return nil }
+func (c *Conn) Send(b []byte) error   { return nil }
+func (c *Conn) Get(key string) []byte { return nil }
+
+func Shutdown(c *Conn) {
+	c.Flush()
+	c.Close()
+}
+
+func Stop(c *Conn) {
+	c.Close()
+	c.Flush()
+}
+
+func Lookup(c *Conn) []byte {
+	return c.Get("foo")
+}
+
+func Forward(p *peer.Peer) {
+	p.Send(p.Recv())
+}
@@ -6,9 +6,13 @@ package check
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
+	"go/token"
 	"go/types"
 	"sort"
 	"strings"
+
+	"golang.org/x/tools/go/types/typeutil"
 )
 
 type methoder interface {
@@ -33,7 +37,7 @@ func methoderFuncMap(m methoder, skip bool) map[string]string {
 }
 
 func typeFuncMap(t types.Type) map[string]string {
-	switch x := t.(type) {
+	switch x := types.Unalias(t).(type) {
 	case *types.Pointer:
 		return typeFuncMap(x.Elem())
 	case *types.Named:
@@ -41,7 +45,9 @@ func typeFuncMap(t types.Type) map[string]string {
 		if types.IsInterface(u) {
 			return typeFuncMap(u)
 		}
-		return methoderFuncMap(x, true)
+		return methodSetFuncMap(x)
+	case *types.Struct:
+		return methodSetFuncMap(x)
 	case *types.Interface:
 		return methoderFuncMap(x, false)
 	default:
@@ -49,6 +55,22 @@ func typeFuncMap(t types.Type) map[string]string {
 	}
 }
 
+// methodSetFuncMap is like methoderFuncMap with skip for a concrete
+// type, but it also includes the methods promoted from its embedded
+// fields, as well as those declared on a pointer to it.
+func methodSetFuncMap(t types.Type) map[string]string {
+	mset := typeutil.IntuitiveMethodSet(t, nil)
+	ifuncs := make(map[string]string, len(mset))
+	for _, sel := range mset {
+		f := sel.Obj().(*types.Func)
+		if !f.Exported() {
+			continue
+		}
+		ifuncs[f.Name()] = signString(sel.Type().(*types.Signature))
+	}
+	return ifuncs
+}
+
 func funcMapString(iface map[string]string) string {
 	fnames := make([]string, 0, len(iface))
 	for fname := range iface {
@@ -86,14 +108,16 @@ func signString(sign *types.Signature) string {
 }
 
 func interesting(t types.Type) bool {
-	switch x := t.(type) {
+	switch x := types.Unalias(t).(type) {
 	case *types.Interface:
 		return x.NumMethods() > 1
 	case *types.Named:
 		if u := x.Underlying(); types.IsInterface(u) {
 			return interesting(u)
 		}
-		return x.NumMethods() >= 1
+		return len(typeutil.IntuitiveMethodSet(x, nil)) >= 1
+	case *types.Struct:
+		return len(typeutil.IntuitiveMethodSet(x, nil)) >= 1
 	case *types.Pointer:
 		return interesting(x.Elem())
 	default:
@@ -111,9 +135,36 @@ func anyInteresting(params *types.Tuple) bool {
 	return false
 }
 
-func fromScope(scope *types.Scope) (ifaces map[string]string, funcs map[string]bool) {
-	ifaces = make(map[string]string)
-	funcs = make(map[string]bool)
+// ifaceSigns adds the signatures of the methods of iface that
+// implementations could be mistaken for to funcs, and returns the
+// string for its method set. It is empty if iface has no methods or
+// can only be used as a constraint.
+func ifaceSigns(iface *types.Interface, funcs map[string]bool) string {
+	if !iface.IsMethodSet() {
+		// a constraint, which can't be used as a type
+		return ""
+	}
+	ms := methoderFuncMap(iface, false)
+	if len(ms) == 0 {
+		return ""
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		sign := iface.Method(i).Type().(*types.Signature)
+		if !anyInteresting(sign.Params()) {
+			continue
+		}
+		funcs[signString(sign)] = true
+	}
+	return funcMapString(ms)
+}
+
+// fromScope indexes the interfaces and func signatures declared in a
+// package scope.
+func fromScope(scope *types.Scope) *pkgIndex {
+	idx := &pkgIndex{
+		Ifaces: make(map[string][]string),
+		Funcs:  make(map[string]bool),
+	}
 	for _, name := range scope.Names() {
 		tn, ok := scope.Lookup(name).(*types.TypeName)
 		if !ok {
@@ -121,30 +172,63 @@ func fromScope(scope *types.Scope) (ifaces map[string]string, funcs map[string]b
 		}
 		switch x := tn.Type().Underlying().(type) {
 		case *types.Interface:
-			iface := methoderFuncMap(x, false)
-			if len(iface) == 0 {
-				continue
-			}
-			for i := 0; i < x.NumMethods(); i++ {
-				f := x.Method(i)
-				sign := f.Type().(*types.Signature)
-				if !anyInteresting(sign.Params()) {
-					continue
+			if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+				// its method set depends on the type arguments
+				if x.IsMethodSet() && x.NumMethods() > 0 {
+					idx.Generic = append(idx.Generic, tn.Name())
 				}
-				funcs[signString(sign)] = true
+				continue
 			}
-			s := funcMapString(iface)
-			if _, e := ifaces[s]; !e {
-				ifaces[s] = tn.Name()
+			s := ifaceSigns(x, idx.Funcs)
+			if s == "" {
+				continue
 			}
+			idx.Ifaces[s] = append(idx.Ifaces[s], tn.Name())
 		case *types.Signature:
 			if !anyInteresting(x.Params()) {
 				continue
 			}
-			funcs[signString(x)] = true
+			idx.Funcs[signString(x)] = true
+		}
+	}
+	return idx
+}
+
+// deprecatedTypes returns the names of the types declared in files
+// whose doc comment has a paragraph starting with "Deprecated: ".
+func deprecatedTypes(files []*ast.File) []string {
+	var names []string
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gd.Specs) == 1 {
+					doc = gd.Doc
+				}
+				if isDeprecated(doc) {
+					names = append(names, ts.Name.Name)
+				}
+			}
 		}
 	}
-	return ifaces, funcs
+	return names
+}
+
+func isDeprecated(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, par := range strings.Split(doc.Text(), "\n\n") {
+		if strings.HasPrefix(par, "Deprecated: ") {
+			return true
+		}
+	}
+	return false
 }
 
 func mentionsName(fname, name string) bool {
@@ -158,7 +242,7 @@ func mentionsName(fname, name string) bool {
 
 func typeNamed(t types.Type) *types.Named {
 	for {
-		switch x := t.(type) {
+		switch x := types.Unalias(t).(type) {
 		case *types.Named:
 			return x
 		case *types.Pointer:
@@ -0,0 +1,126 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package check
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// Diagnostic explains why a suggestion was dropped, as returned by
+// Checker.Diagnostics.
+type Diagnostic struct {
+	Pos     token.Pos
+	Message string
+}
+
+// Diagnostics returns why the suggestions that didn't hold up against
+// the real method sets or the comparisons with nil were dropped by the
+// last call to Check, sorted by position.
+func (c *Checker) Diagnostics() []Diagnostic {
+	return c.diagnostics
+}
+
+// satisfies reports whether a value of the type of vr can be used as
+// iface, instantiated with targs if generic. The methods called on a
+// variable aren't always in the method set of its type, like those with
+// a pointer receiver called on an addressable value. If it can't, the
+// reason is recorded as a diagnostic.
+func (c *Checker) satisfies(vr *types.Var, iface *types.TypeName, targs []types.Type) bool {
+	it := iface.Type()
+	if len(targs) > 0 {
+		inst, err := types.Instantiate(nil, it, targs, false)
+		if err != nil {
+			c.reject(vr, iface, targs, "%v", err)
+			return false
+		}
+		it = inst
+	}
+	t := vr.Type()
+	if types.AssignableTo(t, it) {
+		return true
+	}
+	ityp, ok := it.Underlying().(*types.Interface)
+	if !ok {
+		c.reject(vr, iface, targs, "not an interface")
+		return false
+	}
+	why := "not assignable"
+	if m, wrongType := types.MissingMethod(t, ityp, true); m != nil {
+		// a method with a pointer receiver counts as the wrong type
+		switch {
+		case types.Implements(types.NewPointer(t), ityp):
+			why = fmt.Sprintf("method %s has a pointer receiver", m.Name())
+		case wrongType:
+			why = fmt.Sprintf("wrong type for method %s", m.Name())
+		default:
+			why = fmt.Sprintf("missing method %s", m.Name())
+		}
+	}
+	c.reject(vr, iface, targs, "%s does not satisfy it (%s)", c.typeString(vr.Type()), why)
+	return false
+}
+
+// nilSafe reports whether vr can be narrowed to iface without changing
+// the meaning of its comparisons with nil, as recorded in usage. Once
+// converted to an interface, a nil pointer or map is no longer equal to
+// nil, which is the typed nil trap. If it can't, each of the
+// comparisons is recorded as a diagnostic.
+func (c *Checker) nilSafe(vr *types.Var, usage *varUsage, iface *types.TypeName, targs []types.Type) bool {
+	if types.IsInterface(vr.Type()) {
+		return true
+	}
+	checks := nilChecksOf(usage, make(map[*varUsage]bool))
+	if len(checks) == 0 {
+		return true
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i] < checks[j] })
+	if !c.rejected[vr] {
+		c.rejected[vr] = true
+		for _, pos := range checks {
+			c.diagnose(vr, iface, targs, pos, "comparing it with nil would miss a nil %s", c.typeString(vr.Type()))
+		}
+	}
+	return false
+}
+
+// nilChecksOf returns the comparisons with nil of a variable and of
+// those it's assigned to, which hold the same value.
+func nilChecksOf(usage *varUsage, seen map[*varUsage]bool) []token.Pos {
+	if seen[usage] {
+		return nil
+	}
+	seen[usage] = true
+	checks := append([]token.Pos(nil), usage.nilChecks...)
+	for to := range usage.assigned {
+		checks = append(checks, nilChecksOf(to, seen)...)
+	}
+	return checks
+}
+
+// reject records why iface was not suggested for vr, once per variable.
+func (c *Checker) reject(vr *types.Var, iface *types.TypeName, targs []types.Type, format string, args ...any) {
+	if c.rejected[vr] {
+		return
+	}
+	c.rejected[vr] = true
+	c.diagnose(vr, iface, targs, vr.Pos(), format, args...)
+}
+
+func (c *Checker) diagnose(vr *types.Var, iface *types.TypeName, targs []types.Type, pos token.Pos, format string, args ...any) {
+	name := c.typeName(iface, targs)
+	if embeds := c.composed[iface]; embeds != nil && !c.synthesized[iface] {
+		name = c.composedString(embeds)
+	}
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		Pos:     pos,
+		Message: fmt.Sprintf("%s can't be %s: ", vr.Name(), name) + fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *Checker) typeString(t types.Type) string {
+	return types.TypeString(t, types.RelativeTo(c.pkg))
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package main
+
+import (
+	"encoding/json"
+	"go/token"
+	"go/types"
+	"io"
+
+	"mvdan.cc/interfacer/check"
+)
+
+// jsonIssue is the object printed for each issue with -json. Its field
+// names are part of the output format, so they must not change.
+type jsonIssue struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+
+	Func  string `json:"func"`
+	Param string `json:"param"`
+	Type  string `json:"type"`
+
+	// Struct is only set for struct fields, in which case Param is the
+	// field and Func is empty.
+	Struct string `json:"struct,omitempty"`
+	// TypeParam is only set for type params whose constraint can be
+	// narrowed, in which case Param is empty and Type is the
+	// constraint.
+	TypeParam string `json:"typeparam,omitempty"`
+
+	// Iface is not set if an anonymous interface is suggested with
+	// -compose, in which case Embeds holds what it embeds.
+	Iface   *jsonIface  `json:"iface,omitempty"`
+	Embeds  []jsonIface `json:"embeds,omitempty"`
+	Methods []string    `json:"methods"`
+
+	// Superset is only set with -superset, if Iface has more methods
+	// than those used.
+	Superset bool `json:"superset,omitempty"`
+	// Alternatives is only set with -alts, holding the other
+	// interfaces with the same methods, best first.
+	Alternatives []jsonIface `json:"alternatives,omitempty"`
+
+	// Sink is only set if the param reaches a func observing it through
+	// reflection, as configured with -sinks.
+	Sink string `json:"sink,omitempty"`
+
+	// Decl is only set for new interfaces proposed with -synth.
+	Decl string `json:"decl,omitempty"`
+}
+
+type jsonIface struct {
+	Pkg  string `json:"pkg"`
+	Name string `json:"name"`
+
+	// Args is only set for generic interfaces, holding the type
+	// arguments to instantiate them with.
+	Args []string `json:"args,omitempty"`
+}
+
+func newJSONIface(tn *types.TypeName) jsonIface {
+	ji := jsonIface{Name: tn.Name()}
+	if pkg := tn.Pkg(); pkg != nil {
+		ji.Pkg = pkg.Path()
+	}
+	return ji
+}
+
+func writeJSON(w io.Writer, position func(token.Pos) token.Position, issues []check.Issue) error {
+	enc := json.NewEncoder(w)
+	for _, issue := range issues {
+		pos := position(issue.Pos())
+		ji := jsonIssue{
+			File:    pos.Filename,
+			Line:    pos.Line,
+			Column:  pos.Column,
+			Func:    issue.Func,
+			Struct:  issue.Struct,
+			Methods: issue.Methods,
+			Sink:    issue.Sink,
+			Decl:    issue.Decl,
+		}
+		ji.Superset = issue.Superset
+		for _, alt := range issue.Alternatives {
+			ji.Alternatives = append(ji.Alternatives, newJSONIface(alt))
+		}
+		if issue.Iface != nil {
+			iface := newJSONIface(issue.Iface)
+			for _, targ := range issue.IfaceArgs {
+				iface.Args = append(iface.Args, types.TypeString(targ, nil))
+			}
+			ji.Iface = &iface
+		}
+		for _, embed := range issue.Embeds {
+			ji.Embeds = append(ji.Embeds, newJSONIface(embed))
+		}
+		if tp := issue.TypeParam; tp != nil {
+			ji.TypeParam = tp.Obj().Name()
+			ji.Type = types.TypeString(tp.Constraint(), nil)
+		} else {
+			ji.Param = issue.Param.Name()
+			ji.Type = types.TypeString(issue.Param.Type(), nil)
+		}
+		if err := enc.Encode(ji); err != nil {
+			return err
+		}
+	}
+	return nil
+}
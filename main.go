@@ -6,21 +6,133 @@ package main // import "mvdan.cc/interfacer"
 import (
 	"flag"
 	"fmt"
+	"go/token"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"mvdan.cc/interfacer/check"
 )
 
-var _ = flag.Bool("v", false, "print the names of packages as they are checked")
+var (
+	_ = flag.Bool("v", false, "print the names of packages as they are checked")
+
+	jsonOut  = flag.Bool("json", false, "print issues as JSON objects, one per line")
+	sarifOut = flag.Bool("sarif", false, "print issues as a SARIF 2.1.0 log")
+
+	write   = flag.Bool("w", false, "apply the suggestions to the source files")
+	diffOut = flag.Bool("d", false, "print a diff of the suggestions instead of the issues")
+
+	explain = flag.Bool("explain", false, "print why suggestions were dropped to stderr")
+
+	config check.Config
+)
+
+func init() {
+	config.RegisterFlags(flag.CommandLine)
+}
 
 func main() {
 	flag.Parse()
-	lines, err := check.CheckArgs(flag.Args())
-	if err != nil {
+	if err := run(flag.Args()); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	for _, line := range lines {
-		fmt.Println(line)
+}
+
+func run(args []string) error {
+	if *jsonOut && *sarifOut {
+		return fmt.Errorf("-json and -sarif are mutually exclusive")
+	}
+	if *diffOut && (*jsonOut || *sarifOut) {
+		return fmt.Errorf("-d cannot be used with -json or -sarif")
+	}
+	pkgs, dependents, prog, err := check.LoadDependents(args, config.Dependents)
+	if err != nil {
+		return err
+	}
+	c := &check.Checker{Config: config}
+	c.Packages(pkgs)
+	c.Dependents(dependents)
+	c.ProgramSSA(prog)
+	issues, err := c.Check()
+	if err != nil {
+		return err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	relPath := func(name string) string {
+		if strings.HasPrefix(name, wd) {
+			return name[len(wd)+1:]
+		}
+		return name
+	}
+	position := func(pos token.Pos) token.Position {
+		p := prog.Fset.Position(pos)
+		p.Filename = relPath(p.Filename)
+		return p
+	}
+	if *explain {
+		for _, d := range c.Diagnostics() {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", position(d.Pos), d.Message)
+		}
+	}
+	if *write || *diffOut {
+		changed, err := check.Rewrite(pkgs, issues)
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(changed))
+		for name := range changed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := rewriteFile(name, relPath(name), changed[name]); err != nil {
+				return err
+			}
+		}
+		if *diffOut {
+			return nil
+		}
+	}
+	switch {
+	case *jsonOut:
+		return writeJSON(os.Stdout, position, issues)
+	case *sarifOut:
+		return writeSARIF(os.Stdout, wd, position, issues)
+	}
+	for _, issue := range issues {
+		fmt.Printf("%s: %s\n", position(issue.Pos()), issue.Message())
+	}
+	return nil
+}
+
+// rewriteFile prints the diff for a rewritten file with -d, and writes
+// its new contents with -w.
+func rewriteFile(name, rel string, src []byte) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	if *diffOut {
+		old, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(rel) {
+			// works with both "patch -p0" and "git apply"
+			rel = "./" + filepath.ToSlash(rel)
+		}
+		if _, err := os.Stdout.Write(unifiedDiff(rel, old, src)); err != nil {
+			return err
+		}
+	}
+	if *write {
+		return os.WriteFile(name, src, info.Mode().Perm())
 	}
+	return nil
 }
@@ -0,0 +1,188 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package main
+
+import (
+	"bytes"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mvdan.cc/interfacer/check"
+)
+
+// checkOutput checks the fixture in testdata/output and returns the
+// output of write for its issues, with file paths relative to it.
+func checkOutput(t *testing.T, write func(io.Writer, func(token.Pos) token.Position, []check.Issue) error) []byte {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join("testdata", "output")
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	pkgs, prog, err := check.LoadArgs([]string{"."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := new(check.Checker)
+	c.Packages(pkgs)
+	c.ProgramSSA(prog)
+	issues, err := c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	position := func(pos token.Pos) token.Position {
+		p := prog.Fset.Position(pos)
+		p.Filename = filepath.Base(p.Filename)
+		return p
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, position, issues); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+	want, err := os.ReadFile(filepath.Join("testdata", golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Output mismatch in %s:\nExpected:\n%s\nGot:\n%s", golden, want, got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	// the field names are part of the output format
+	got := checkOutput(t, writeJSON)
+	testGolden(t, "output.json.golden", got)
+}
+
+func TestSARIF(t *testing.T) {
+	got := checkOutput(t, func(w io.Writer, position func(token.Pos) token.Position, issues []check.Issue) error {
+		// a fixed root, so that the output doesn't depend on where
+		// the tests run
+		return writeSARIF(w, "/src", position, issues)
+	})
+	testGolden(t, "output.sarif.golden", got)
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	numbers := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n"
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"Same", "a\nb\n", "a\nb\n", ""},
+		{
+			"Merged",
+			numbers,
+			"1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\neleven\n12\n13\n14\n15\n16\n",
+			`@@ -1,14 +1,14 @@
+ 1
+ 2
+ 3
+-4
++four
+ 5
+ 6
+ 7
+ 8
+ 9
+ 10
+-11
++eleven
+ 12
+ 13
+ 14
+`,
+		},
+		{
+			"Split",
+			numbers,
+			"1\n2\n3\nfour\n5\n6\n7\n8\n9\n10\n11\ntwelve\n13\n14\n15\n16\n",
+			`@@ -1,7 +1,7 @@
+ 1
+ 2
+ 3
+-4
++four
+ 5
+ 6
+ 7
+@@ -9,7 +9,7 @@
+ 9
+ 10
+ 11
+-12
++twelve
+ 13
+ 14
+ 15
+`,
+		},
+		{
+			"NoTrailingNewline",
+			"a\nb",
+			"a\nc",
+			`@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+		{
+			"TrailingNewlineRemoved",
+			"a\nb\n",
+			"a\nb",
+			`@@ -1,2 +1,2 @@
+ a
+-b
++b
+\ No newline at end of file
+`,
+		},
+		{
+			"InsertAtStart",
+			"a\nb\n",
+			"x\na\nb\n",
+			`@@ -1,2 +1,3 @@
++x
+ a
+ b
+`,
+		},
+		{
+			"InsertIntoEmpty",
+			"",
+			"x\n",
+			`@@ -0,0 +1 @@
++x
+`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			want := tc.want
+			if want != "" {
+				want = "--- f.go\n+++ f.go\n" + want
+			}
+			got := string(unifiedDiff("f.go", []byte(tc.old), []byte(tc.new)))
+			if got != want {
+				t.Fatalf("Diff mismatch:\nExpected:\n%s\nGot:\n%s", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2015, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go/token"
+	"go/types"
+	"io"
+	"net/url"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"mvdan.cc/interfacer/check"
+)
+
+// The subset of SARIF 2.1.0 that we produce. See
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+
+	sarifRuleID   = "interfacer/param-interface"
+	sarifSrcRoot  = "%SRCROOT%"
+	sarifPrintKey = "interfacer/v1"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Version        string      `json:"version,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name"`
+	ShortDescription sarifText              `json:"shortDescription"`
+	MessageStrings   map[string]sarifText   `json:"messageStrings"`
+	DefaultConfig    sarifRuleConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifRuleConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifMessage struct {
+	Text      string   `json:"text"`
+	ID        string   `json:"id"`
+	Arguments []string `json:"arguments"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+	Region           sarifRegion      `json:"region"`
+}
+
+type sarifArtifactLoc struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// toolVersion returns the module version interfacer was built from, if
+// known.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "(devel)" {
+		return ""
+	}
+	return info.Main.Version
+}
+
+// fileURI returns a relative or absolute file path as a URI reference.
+func fileURI(path string) string {
+	u := url.URL{Path: filepath.ToSlash(path)}
+	if filepath.IsAbs(path) {
+		u.Scheme = "file"
+	}
+	return u.String()
+}
+
+// fingerprint identifies an issue independently of its line, so that
+// code scanning can match results across runs as the code around them
+// moves.
+func fingerprint(parts ...string) string {
+	sum := sha256.New()
+	for _, s := range parts {
+		io.WriteString(sum, s)
+		sum.Write([]byte{0})
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// typeArgsString formats type arguments like "[K, V]", fully qualified.
+func typeArgsString(targs []types.Type) string {
+	strs := make([]string, len(targs))
+	for i, t := range targs {
+		strs[i] = types.TypeString(t, nil)
+	}
+	return "[" + strings.Join(strs, ", ") + "]"
+}
+
+// ifaceString returns the suggested type of an issue, qualified by
+// full package paths.
+func ifaceString(issue check.Issue) string {
+	if issue.Iface == nil {
+		names := make([]string, len(issue.Embeds))
+		for i, embed := range issue.Embeds {
+			names[i] = qualifiedName(embed)
+		}
+		return "interface{ " + strings.Join(names, "; ") + " }"
+	}
+	iface := qualifiedName(issue.Iface)
+	if len(issue.IfaceArgs) > 0 {
+		iface += typeArgsString(issue.IfaceArgs)
+	}
+	return iface
+}
+
+func qualifiedName(tn *types.TypeName) string {
+	if pkg := tn.Pkg(); pkg != nil {
+		return pkg.Path() + "." + tn.Name()
+	}
+	return tn.Name()
+}
+
+func writeSARIF(w io.Writer, wd string, position func(token.Pos) token.Position, issues []check.Issue) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "interfacer",
+			InformationURI: "https://github.com/mvdan/interfacer",
+			Version:        toolVersion(),
+			Rules: []sarifRule{{
+				ID:               sarifRuleID,
+				Name:             "ParamCanBeInterface",
+				ShortDescription: sarifText{"Parameter type is more specific than necessary"},
+				MessageStrings: map[string]sarifText{
+					"default": {"{0} can be {1}"},
+				},
+				DefaultConfig: sarifRuleConfiguration{Level: "warning"},
+			}},
+		}},
+		OriginalURIBaseIDs: map[string]sarifArtifactLoc{
+			sarifSrcRoot: {URI: fileURI(wd) + "/"},
+		},
+		Results: []sarifResult{},
+	}
+	for _, issue := range issues {
+		start := position(issue.TypeExpr.Pos())
+		end := position(issue.TypeExpr.End())
+		loc := sarifArtifactLoc{URI: fileURI(start.Filename)}
+		if !filepath.IsAbs(start.Filename) {
+			loc.URIBaseID = sarifSrcRoot
+		}
+		logical := sarifLogicalLocation{Name: issue.Func, Kind: "function"}
+		if issue.Struct != "" {
+			logical = sarifLogicalLocation{Name: issue.Struct, Kind: "type"}
+		}
+		iface := ifaceString(issue)
+		var name string
+		if tp := issue.TypeParam; tp != nil {
+			name = tp.Obj().Name()
+		} else {
+			name = issue.Param.Name()
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    sarifRuleID,
+			RuleIndex: 0,
+			Level:     "warning",
+			Message: sarifMessage{
+				Text:      issue.Message(),
+				ID:        "default",
+				Arguments: []string{name, iface},
+			},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: loc,
+					Region: sarifRegion{
+						StartLine:   start.Line,
+						StartColumn: start.Column,
+						EndLine:     end.Line,
+						EndColumn:   end.Column,
+					},
+				},
+				LogicalLocations: []sarifLogicalLocation{logical},
+			}},
+			PartialFingerprints: map[string]string{
+				sarifPrintKey: fingerprint(start.Filename, issue.Func+issue.Struct,
+					name, iface),
+			},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,24 @@
+package output
+
+import "os"
+
+func Shutdown(f *os.File) {
+	f.Close()
+}
+
+type server struct {
+	conn *os.File
+}
+
+func (s *server) stop() {
+	s.conn.Close()
+}
+
+func CloseAll[C interface {
+	Close() error
+	Name() string
+}](cs []C) {
+	for _, c := range cs {
+		c.Close()
+	}
+}